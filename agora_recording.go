@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RecordingState tracks an Agora Cloud Recording session for a huddle
+// across its acquire -> start -> query -> stop lifecycle, persisted
+// through the Huddle so a restart doesn't lose track of an in-progress
+// recording (see RecordingReconciler).
+type RecordingState struct {
+	ResourceID string         `json:"resource_id"`
+	SID        string         `json:"sid"`
+	Mode       string         `json:"mode"`
+	Storage    StorageConfig  `json:"storage"`
+	Status     string         `json:"status"` // "started" or "stopped"
+	StartedAt  time.Time      `json:"started_at"`
+	StoppedAt  *time.Time     `json:"stopped_at,omitempty"`
+	Files      []RecordedFile `json:"files,omitempty"`
+}
+
+// RecordedFile is one output file reported by Agora's stop/query response.
+type RecordedFile struct {
+	Filename     string `json:"filename"`
+	SizeBytes    int64  `json:"size_bytes"`
+	MixedAllUser bool   `json:"mixed_all_user"`
+}
+
+// StorageConfig is Agora's third-party storage config block (vendor-
+// specific numeric codes per Agora's docs, e.g. 1 = Amazon S3, 2 = GCS).
+type StorageConfig struct {
+	Vendor int    `json:"vendor"`
+	Region int    `json:"region"`
+	Bucket string `json:"bucket"`
+	// AccessKey/SecretKey are credentials for the operator's own storage
+	// bucket - they must never round-trip into a JSON response (recording
+	// status is returned to any huddle participant).
+	AccessKey      string   `json:"-"`
+	SecretKey      string   `json:"-"`
+	FilenamePrefix []string `json:"filename_prefix,omitempty"`
+}
+
+// storageConfigFromEnv builds the default StorageConfig from
+// AGORA_STORAGE_* env vars, overridable per-request.
+func storageConfigFromEnv() StorageConfig {
+	vendor, _ := parseIntEnv("AGORA_STORAGE_VENDOR", 1)
+	region, _ := parseIntEnv("AGORA_STORAGE_REGION", 0)
+	return StorageConfig{
+		Vendor:    vendor,
+		Region:    region,
+		Bucket:    os.Getenv("AGORA_STORAGE_BUCKET"),
+		AccessKey: os.Getenv("AGORA_STORAGE_ACCESS_KEY"),
+		SecretKey: os.Getenv("AGORA_STORAGE_SECRET_KEY"),
+	}
+}
+
+// AgoraRecordingClient wraps Agora's Cloud Recording REST API
+// (https://api.agora.io/v1/apps/{appid}/cloud_recording).
+type AgoraRecordingClient struct {
+	httpClient     *http.Client
+	appID          string
+	customerKey    string
+	customerSecret string
+}
+
+// NewAgoraRecordingClient reads AGORA_CUSTOMER_KEY/AGORA_CUSTOMER_SECRET
+// from the environment.
+func NewAgoraRecordingClient(appID string) (*AgoraRecordingClient, error) {
+	customerKey := os.Getenv("AGORA_CUSTOMER_KEY")
+	customerSecret := os.Getenv("AGORA_CUSTOMER_SECRET")
+	if customerKey == "" || customerSecret == "" {
+		return nil, fmt.Errorf("AGORA_CUSTOMER_KEY and AGORA_CUSTOMER_SECRET are required for cloud recording")
+	}
+	return &AgoraRecordingClient{
+		httpClient:     &http.Client{Timeout: 15 * time.Second},
+		appID:          appID,
+		customerKey:    customerKey,
+		customerSecret: customerSecret,
+	}, nil
+}
+
+func (a *AgoraRecordingClient) baseURL() string {
+	return "https://api.agora.io/v1/apps/" + a.appID + "/cloud_recording"
+}
+
+// doWithRetry posts body to path, retrying on 5xx with exponential
+// backoff (3 attempts, waiting 500ms then 1s) since Agora's recording API is
+// occasionally flaky under load.
+func (a *AgoraRecordingClient) doWithRetry(ctx context.Context, path string, body any) ([]byte, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("agora recording: marshal request: %w", err)
+	}
+
+	const maxAttempts = 3
+	var lastErr error
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		if attempt > 0 {
+			backoff := time.Duration(math.Pow(2, float64(attempt-1))) * 500 * time.Millisecond
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.baseURL()+path, bytes.NewReader(payload))
+		if err != nil {
+			return nil, fmt.Errorf("agora recording: build request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.SetBasicAuth(a.customerKey, a.customerSecret)
+
+		resp, err := a.httpClient.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		respBody, readErr := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if readErr != nil {
+			lastErr = readErr
+			continue
+		}
+
+		if resp.StatusCode >= 500 {
+			lastErr = fmt.Errorf("agora recording: %s returned %d: %s", path, resp.StatusCode, respBody)
+			continue
+		}
+		if resp.StatusCode >= 400 {
+			return nil, fmt.Errorf("agora recording: %s returned %d: %s", path, resp.StatusCode, respBody)
+		}
+		return respBody, nil
+	}
+	return nil, fmt.Errorf("agora recording: %s failed after %d attempts: %w", path, maxAttempts, lastErr)
+}
+
+// Acquire reserves cloud recording resources for a channel/uid pair. It
+// must happen before Start.
+func (a *AgoraRecordingClient) Acquire(ctx context.Context, channelName, uid string) (resourceID string, err error) {
+	respBody, err := a.doWithRetry(ctx, "/acquire", gin.H{
+		"cname": channelName,
+		"uid":   uid,
+		"clientRequest": gin.H{
+			"resourceExpiredHour": 24,
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	var parsed struct {
+		ResourceID string `json:"resourceId"`
+	}
+	if err := json.Unmarshal(respBody, &parsed); err != nil {
+		return "", fmt.Errorf("agora recording: unmarshal acquire response: %w", err)
+	}
+	return parsed.ResourceID, nil
+}
+
+// Start begins recording against a previously acquired resource.
+func (a *AgoraRecordingClient) Start(ctx context.Context, channelName, uid, resourceID, mode string, storage StorageConfig) (sid string, err error) {
+	path := fmt.Sprintf("/resourceid/%s/mode/%s/start", resourceID, mode)
+	respBody, err := a.doWithRetry(ctx, path, gin.H{
+		"cname": channelName,
+		"uid":   uid,
+		"clientRequest": gin.H{
+			"recordingConfig": gin.H{
+				"channelType": 1,
+				"streamTypes": 2,
+				"maxIdleTime": 30,
+			},
+			"storageConfig": gin.H{
+				"vendor":         storage.Vendor,
+				"region":         storage.Region,
+				"bucket":         storage.Bucket,
+				"accessKey":      storage.AccessKey,
+				"secretKey":      storage.SecretKey,
+				"fileNamePrefix": storage.FilenamePrefix,
+			},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	var parsed struct {
+		SID string `json:"sid"`
+	}
+	if err := json.Unmarshal(respBody, &parsed); err != nil {
+		return "", fmt.Errorf("agora recording: unmarshal start response: %w", err)
+	}
+	return parsed.SID, nil
+}
+
+// Query returns Agora's current view of a recording session, used by the
+// reconciliation poller; GET /huddle/:id/recording/status does not call it.
+func (a *AgoraRecordingClient) Query(ctx context.Context, resourceID, sid, mode string) (status string, files []RecordedFile, err error) {
+	path := fmt.Sprintf("/resourceid/%s/sid/%s/mode/%s/query", resourceID, sid, mode)
+	respBody, err := a.doWithRetry(ctx, path, gin.H{})
+	if err != nil {
+		return "", nil, err
+	}
+	var parsed struct {
+		ServerResponse struct {
+			Status   int `json:"status"`
+			FileList []struct {
+				Filename     string `json:"filename"`
+				SliceStartTs int64  `json:"sliceStartTs"`
+				MixedAllUser bool   `json:"mixedAllUser"`
+			} `json:"fileList"`
+		} `json:"serverResponse"`
+	}
+	if err := json.Unmarshal(respBody, &parsed); err != nil {
+		return "", nil, fmt.Errorf("agora recording: unmarshal query response: %w", err)
+	}
+	for _, f := range parsed.ServerResponse.FileList {
+		files = append(files, RecordedFile{Filename: f.Filename, MixedAllUser: f.MixedAllUser})
+	}
+	return strconv.Itoa(parsed.ServerResponse.Status), files, nil
+}
+
+// Stop ends recording and returns the final file list.
+func (a *AgoraRecordingClient) Stop(ctx context.Context, channelName, uid, resourceID, sid, mode string) (files []RecordedFile, err error) {
+	path := fmt.Sprintf("/resourceid/%s/sid/%s/mode/%s/stop", resourceID, sid, mode)
+	respBody, err := a.doWithRetry(ctx, path, gin.H{
+		"cname":         channelName,
+		"uid":           uid,
+		"clientRequest": gin.H{},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var parsed struct {
+		ServerResponse struct {
+			FileList []struct {
+				Filename     string `json:"filename"`
+				MixedAllUser bool   `json:"mixedAllUser"`
+			} `json:"fileList"`
+		} `json:"serverResponse"`
+	}
+	if err := json.Unmarshal(respBody, &parsed); err != nil {
+		return nil, fmt.Errorf("agora recording: unmarshal stop response: %w", err)
+	}
+	for _, f := range parsed.ServerResponse.FileList {
+		files = append(files, RecordedFile{Filename: f.Filename, MixedAllUser: f.MixedAllUser})
+	}
+	return files, nil
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"golang.org/x/time/rate"
+)
+
+// ChatMessageType identifies the kind of payload broadcast over a
+// huddle's chat WebSocket.
+type ChatMessageType string
+
+const (
+	ChatMessageChat      ChatMessageType = "chat"
+	ChatMessageDanmaku   ChatMessageType = "danmaku"
+	ChatMessagePresence  ChatMessageType = "presence"
+	ChatMessageRecording ChatMessageType = "recording"
+	chatBacklogSize                      = 50
+	chatSendBufferSize                   = 16
+)
+
+// ChatMessage is the JSON payload broadcast to every subscriber of a
+// huddle. Color/Position/DurationMs are only meaningful for danmaku
+// (bullet-chat) messages, which overlay rather than list like ordinary
+// chat.
+type ChatMessage struct {
+	Type       ChatMessageType `json:"type"`
+	UserID     string          `json:"user_id"`
+	Body       string          `json:"body,omitempty"`
+	Timestamp  time.Time       `json:"ts"`
+	Color      string          `json:"color,omitempty"`
+	Position   string          `json:"position,omitempty"`
+	DurationMs int             `json:"duration_ms,omitempty"`
+}
+
+// chatClient is one connected WebSocket subscriber of a huddle's chat.
+type chatClient struct {
+	conn     *websocket.Conn
+	send     chan ChatMessage
+	huddleID string
+	userID   string
+
+	closeMu sync.Mutex
+	closed  bool
+}
+
+// trySend delivers msg to the client unless it's already been closed,
+// dropping the message rather than blocking if send's buffer is full.
+// Guarding the send with closeMu makes it mutually exclusive with close,
+// so broadcast can never write to a channel that Unsubscribe has closed.
+func (c *chatClient) trySend(msg ChatMessage) {
+	c.closeMu.Lock()
+	defer c.closeMu.Unlock()
+	if c.closed {
+		return
+	}
+	select {
+	case c.send <- msg:
+	default:
+		// Slow client; drop rather than block the broadcaster.
+	}
+}
+
+// close closes send exactly once, safe to call concurrently with trySend.
+func (c *chatClient) close() {
+	c.closeMu.Lock()
+	defer c.closeMu.Unlock()
+	if c.closed {
+		return
+	}
+	c.closed = true
+	close(c.send)
+}
+
+// ChatHub fans chat/danmaku/presence messages out to every client
+// subscribed to a huddle, rate-limits senders per user, and replays a
+// bounded backlog to new joiners so they have some context.
+type ChatHub struct {
+	mu       sync.Mutex
+	rooms    map[string]map[*chatClient]bool
+	backlog  map[string][]ChatMessage
+	limiters map[string]*rate.Limiter // keyed by huddleID+":"+userID
+}
+
+// NewChatHub builds an empty hub.
+func NewChatHub() *ChatHub {
+	return &ChatHub{
+		rooms:    make(map[string]map[*chatClient]bool),
+		backlog:  make(map[string][]ChatMessage),
+		limiters: make(map[string]*rate.Limiter),
+	}
+}
+
+// Subscribe registers a client, replays the huddle's backlog to it, and
+// announces its presence to the rest of the room.
+func (h *ChatHub) Subscribe(c *chatClient) {
+	h.mu.Lock()
+	if h.rooms[c.huddleID] == nil {
+		h.rooms[c.huddleID] = make(map[*chatClient]bool)
+	}
+	h.rooms[c.huddleID][c] = true
+	backlog := append([]ChatMessage(nil), h.backlog[c.huddleID]...)
+	h.mu.Unlock()
+
+	for _, msg := range backlog {
+		c.send <- msg
+	}
+
+	h.broadcast(c.huddleID, ChatMessage{Type: ChatMessagePresence, UserID: c.userID, Body: "joined", Timestamp: time.Now().UTC()})
+}
+
+// Unsubscribe removes a client from its room and closes its send channel.
+func (h *ChatHub) Unsubscribe(c *chatClient) {
+	h.mu.Lock()
+	if clients, ok := h.rooms[c.huddleID]; ok {
+		delete(clients, c)
+		if len(clients) == 0 {
+			delete(h.rooms, c.huddleID)
+		}
+	}
+	h.mu.Unlock()
+
+	// Closing happens through c.close(), which serializes against
+	// broadcast's c.trySend so a send can never race a close of the same
+	// channel.
+	c.close()
+
+	h.broadcast(c.huddleID, ChatMessage{Type: ChatMessagePresence, UserID: c.userID, Body: "left", Timestamp: time.Now().UTC()})
+}
+
+// Broadcast validates rate limits, records the message in the huddle's
+// backlog, and fans it out to every subscriber.
+func (h *ChatHub) Broadcast(huddleID string, msg ChatMessage) bool {
+	if !h.allow(huddleID, msg.UserID) {
+		return false
+	}
+	h.broadcast(huddleID, msg)
+	return true
+}
+
+func (h *ChatHub) broadcast(huddleID string, msg ChatMessage) {
+	h.mu.Lock()
+	backlog := append(h.backlog[huddleID], msg)
+	if len(backlog) > chatBacklogSize {
+		backlog = backlog[len(backlog)-chatBacklogSize:]
+	}
+	h.backlog[huddleID] = backlog
+
+	clients := make([]*chatClient, 0, len(h.rooms[huddleID]))
+	for c := range h.rooms[huddleID] {
+		clients = append(clients, c)
+	}
+	h.mu.Unlock()
+
+	for _, c := range clients {
+		c.trySend(msg)
+	}
+}
+
+func (h *ChatHub) allow(huddleID, userID string) bool {
+	key := huddleID + ":" + userID
+
+	h.mu.Lock()
+	limiter, ok := h.limiters[key]
+	if !ok {
+		limiter = rate.NewLimiter(rate.Limit(chatRateLimitPerSecond()), chatRateLimitBurst())
+		h.limiters[key] = limiter
+	}
+	h.mu.Unlock()
+
+	return limiter.Allow()
+}
+
+// CloseHuddle disconnects every client subscribed to a huddle. Called when
+// the huddle ends so clients aren't left talking to a room that no longer
+// exists.
+func (h *ChatHub) CloseHuddle(huddleID string) {
+	h.mu.Lock()
+	clients := h.rooms[huddleID]
+	delete(h.rooms, huddleID)
+	delete(h.backlog, huddleID)
+	h.mu.Unlock()
+
+	for c := range clients {
+		c.conn.Close()
+	}
+}
+
+// CloseUser disconnects a single participant's chat socket in a huddle,
+// e.g. because Store.Leave removed them from the room.
+func (h *ChatHub) CloseUser(huddleID, userID string) {
+	h.mu.Lock()
+	var toClose []*chatClient
+	for c := range h.rooms[huddleID] {
+		if c.userID == userID {
+			toClose = append(toClose, c)
+		}
+	}
+	h.mu.Unlock()
+
+	for _, c := range toClose {
+		c.conn.Close()
+	}
+}
+
+// WatchStore subscribes to the huddle store's event stream and keeps the
+// chat hub in sync with huddle lifecycle across every replica: if another
+// replica ends a huddle or removes a participant, this one's sockets are
+// torn down too.
+func (h *ChatHub) WatchStore(ctx context.Context, store Store) {
+	events, err := store.Watch(ctx)
+	if err != nil {
+		log.Printf("chat: watch store: %v", err)
+		return
+	}
+	for evt := range events {
+		switch evt.Type {
+		case EventHuddleEnded:
+			h.CloseHuddle(evt.HuddleID)
+		case EventParticipantLeft:
+			h.CloseUser(evt.HuddleID, evt.UserID)
+		}
+	}
+}
+
+func chatRateLimitPerSecond() float64 {
+	v, _ := parseIntEnv("CHAT_RATE_LIMIT_PER_SEC", 5)
+	return float64(v)
+}
+
+func chatRateLimitBurst() int {
+	v, _ := parseIntEnv("CHAT_RATE_LIMIT_BURST", 10)
+	return v
+}
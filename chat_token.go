@@ -0,0 +1,72 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// chatTokenClaims is the payload signed into a chat token. It is
+// deliberately minimal (no JWT library pulled in for this) since all a
+// chat-token needs to prove is "this user may join this huddle's chat
+// until this time".
+type chatTokenClaims struct {
+	HuddleID  string `json:"huddle_id"`
+	UserID    string `json:"user_id"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// signChatToken issues a short-lived token scoped to one huddle and user,
+// signed with APP_CERTIFICATE so only this service can mint valid ones.
+func signChatToken(huddleID, userID string, ttl time.Duration) (string, error) {
+	claims := chatTokenClaims{
+		HuddleID:  huddleID,
+		UserID:    userID,
+		ExpiresAt: time.Now().UTC().Add(ttl).Unix(),
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("chat token: marshal claims: %w", err)
+	}
+
+	encodedPayload := base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, []byte(appCertificate))
+	mac.Write([]byte(encodedPayload))
+	signature := base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+
+	return encodedPayload + "." + signature, nil
+}
+
+// verifyChatToken checks the signature and expiry, returning the claims.
+func verifyChatToken(token string) (*chatTokenClaims, error) {
+	parts := strings.SplitN(token, ".", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("malformed chat token")
+	}
+	encodedPayload, signature := parts[0], parts[1]
+
+	mac := hmac.New(sha256.New, []byte(appCertificate))
+	mac.Write([]byte(encodedPayload))
+	expectedSignature := base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+	if subtle.ConstantTimeCompare([]byte(signature), []byte(expectedSignature)) != 1 {
+		return nil, fmt.Errorf("invalid chat token signature")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return nil, fmt.Errorf("chat token: decode payload: %w", err)
+	}
+	var claims chatTokenClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("chat token: unmarshal claims: %w", err)
+	}
+	if time.Now().UTC().Unix() > claims.ExpiresAt {
+		return nil, fmt.Errorf("chat token expired")
+	}
+	return &claims, nil
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const chatTokenTTL = 5 * time.Minute
+
+var chatUpgrader = websocket.Upgrader{
+	// The service is already behind the permissive CORS middleware in
+	// main.go, and the chat token is the actual authentication boundary.
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// getChatToken issues a short-lived token scoped to one huddle and user,
+// required as a query param by huddleChatWebSocket.
+func getChatToken(c *gin.Context) {
+	huddleID := c.Param("id")
+
+	if _, err := huddleStore.Get(c.Request.Context(), huddleID); err != nil {
+		c.JSON(404, gin.H{"error": "huddle not found"})
+		return
+	}
+
+	// userID comes from the verified auth token, not a client-supplied
+	// field, so a caller can't mint a chat token impersonating someone else.
+	token, err := signChatToken(huddleID, authUserID(c), chatTokenTTL)
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"chat_token": token, "expires_in": int(chatTokenTTL.Seconds())})
+}
+
+// huddleChatWebSocket upgrades the connection and wires it into chatHub.
+// Clients authenticate with ?token=<chat-token from getChatToken>.
+func huddleChatWebSocket(c *gin.Context) {
+	huddleID := c.Param("id")
+
+	claims, err := verifyChatToken(c.Query("token"))
+	if err != nil {
+		c.JSON(401, gin.H{"error": err.Error()})
+		return
+	}
+	if claims.HuddleID != huddleID {
+		c.JSON(401, gin.H{"error": "chat token is not valid for this huddle"})
+		return
+	}
+
+	conn, err := chatUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Printf("chat: upgrade failed: %v", err)
+		return
+	}
+
+	client := &chatClient{
+		conn:     conn,
+		send:     make(chan ChatMessage, chatSendBufferSize),
+		huddleID: huddleID,
+		userID:   claims.UserID,
+	}
+	// The pump must be draining client.send before Subscribe replays the
+	// backlog, or a room with more than chatSendBufferSize queued messages
+	// deadlocks this goroutine forever.
+	go writeChatPump(client)
+	chatHub.Subscribe(client)
+
+	readChatPump(client)
+}
+
+// writeChatPump drains the client's send channel to the socket until it's
+// closed by Unsubscribe.
+func writeChatPump(c *chatClient) {
+	defer c.conn.Close()
+	for msg := range c.send {
+		if err := c.conn.WriteJSON(msg); err != nil {
+			return
+		}
+	}
+}
+
+// readChatPump reads client-submitted chat/danmaku messages until the
+// socket errors or closes, then unsubscribes the client.
+func readChatPump(c *chatClient) {
+	defer chatHub.Unsubscribe(c)
+	defer c.conn.Close()
+
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var incoming ChatMessage
+		if err := json.Unmarshal(data, &incoming); err != nil {
+			continue
+		}
+		if incoming.Type != ChatMessageChat && incoming.Type != ChatMessageDanmaku {
+			continue // clients may only originate chat/danmaku; presence is server-driven
+		}
+		incoming.UserID = c.userID
+		incoming.Timestamp = time.Now().UTC()
+
+		chatHub.Broadcast(c.huddleID, incoming)
+	}
+}
@@ -1,201 +1,33 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
 	"strconv"
-	"sync"
 	"time"
 
 	rtctokenbuilder2 "github.com/AgoraIO-Community/go-tokenbuilder/rtctokenbuilder"
 	rtmtokenbuilder2 "github.com/AgoraIO-Community/go-tokenbuilder/rtmtokenbuilder"
 
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 	"github.com/joho/godotenv"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 var appID, appCertificate string
 
-// In-memory huddle storage
-var huddleStore *HuddleStore
+// huddleStore is the configured Store backend, selected via
+// HUDDLE_STORE_DRIVER. See store.go.
+var huddleStore Store
 
-// Huddle represents a huddle room
-type Huddle struct {
-	ID           string    `json:"huddle_id"`
-	ChannelName  string    `json:"channel_name"`
-	CreatedBy    string    `json:"created_by"`
-	CreatedAt    time.Time `json:"created_at"`
-	Participants []string  `json:"participants"`
-}
-
-// HuddleStore manages huddles in memory with thread-safe operations
-type HuddleStore struct {
-	mu      sync.RWMutex
-	huddles map[string]*Huddle
-}
-
-// NewHuddleStore creates a new in-memory huddle store
-func NewHuddleStore() *HuddleStore {
-	return &HuddleStore{
-		huddles: make(map[string]*Huddle),
-	}
-}
-
-func (s *HuddleStore) Create(createdBy string) *Huddle {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	huddleID := uuid.New().String()
-	huddle := &Huddle{
-		ID:           huddleID,
-		ChannelName:  fmt.Sprintf("huddle_%s", huddleID[:8]),
-		CreatedBy:    createdBy,
-		CreatedAt:    time.Now().UTC(),
-		Participants: []string{},
-	}
-	s.huddles[huddleID] = huddle
-	return huddle
-}
-
-func (s *HuddleStore) GetByChannel(channelName string) (*Huddle, bool) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
-	for _, huddle := range s.huddles {
-		if huddle.ChannelName == channelName {
-			return huddle, true
-		}
-	}
-	return nil, false
-}
-
-func (s *HuddleStore) Get(huddleID string) (*Huddle, bool) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
-	huddle, exists := s.huddles[huddleID]
-	return huddle, exists
-}
-
-func (s *HuddleStore) List() []*Huddle {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
-	huddles := make([]*Huddle, 0, len(s.huddles))
-	for _, huddle := range s.huddles {
-		huddles = append(huddles, huddle)
-	}
-	return huddles
-}
-
-func (s *HuddleStore) Join(huddleID, userID string) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	huddle, exists := s.huddles[huddleID]
-	if !exists {
-		return fmt.Errorf("huddle not found")
-	}
-
-	// Check if already joined
-	for _, participant := range huddle.Participants {
-		if participant == userID {
-			return nil // Already in huddle
-		}
-	}
-
-	huddle.Participants = append(huddle.Participants, userID)
-	return nil
-}
-
-func (s *HuddleStore) Leave(huddleID, userID string) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	huddle, exists := s.huddles[huddleID]
-	if !exists {
-		return fmt.Errorf("huddle not found")
-	}
+// chatHub fans out per-huddle chat/danmaku/presence messages. See chat.go.
+var chatHub *ChatHub
 
-	// Remove participant
-	for i, participant := range huddle.Participants {
-		if participant == userID {
-			huddle.Participants = append(huddle.Participants[:i], huddle.Participants[i+1:]...)
-			return nil
-		}
-	}
-
-	return fmt.Errorf("user not in huddle")
-}
-
-func (s *HuddleStore) End(huddleID string) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	if _, exists := s.huddles[huddleID]; !exists {
-		return fmt.Errorf("huddle not found")
-	}
-
-	delete(s.huddles, huddleID)
-	return nil
-}
-
-func (s *HuddleStore) EndByChannel(channelName string) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	for id, huddle := range s.huddles {
-		if huddle.ChannelName == channelName {
-			delete(s.huddles, id)
-			return nil
-		}
-	}
-	return fmt.Errorf("huddle not found")
-}
-
-func (s *HuddleStore) GetOrCreate(channelName, userID string) *Huddle {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	// Check if huddle exists
-	for _, huddle := range s.huddles {
-		if huddle.ChannelName == channelName {
-			return huddle
-		}
-	}
-
-	// Create new huddle
-	huddleID := uuid.New().String()
-	huddle := &Huddle{
-		ID:           huddleID,
-		ChannelName:  channelName,
-		CreatedBy:    userID,
-		CreatedAt:    time.Now().UTC(),
-		Participants: []string{},
-	}
-	s.huddles[huddleID] = huddle
-	return huddle
-}
-
-func (s *HuddleStore) JoinByChannel(channelName, userID string) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	for _, huddle := range s.huddles {
-		if huddle.ChannelName == channelName {
-			// Check if already joined
-			for _, participant := range huddle.Participants {
-				if participant == userID {
-					return // Already in huddle
-				}
-			}
-			huddle.Participants = append(huddle.Participants, userID)
-			return
-		}
-	}
-}
+// webhookDispatcher delivers signed huddle lifecycle events to registered
+// operator URLs. See webhook.go/webhook_dispatcher.go.
+var webhookDispatcher *WebhookDispatcher
 
 func main() {
 	// Load .env file if present (silent fail if not found)
@@ -208,9 +40,39 @@ func main() {
 		log.Fatal("Error: APP_ID and APP_CERTIFICATE environment variables are required.")
 	}
 
-	// Initialize in-memory huddle store
-	huddleStore = NewHuddleStore()
-	log.Println("Initialized in-memory huddle store")
+	// Initialize the huddle store. Defaults to in-memory; set
+	// HUDDLE_STORE_DRIVER to etcd/postgres/redis to run multiple
+	// replicas behind a load balancer.
+	driver := os.Getenv("HUDDLE_STORE_DRIVER")
+	store, err := NewStore(driver)
+	if err != nil {
+		log.Fatalf("Error initializing huddle store: %v", err)
+	}
+	huddleStore = store
+	defer huddleStore.Close()
+	log.Printf("Initialized huddle store (driver=%q)", driver)
+
+	// Reap huddles that have sat empty past their idle threshold or
+	// outlived their ExpiresAt so abandoned rooms don't accumulate forever.
+	reaperInterval := envDuration("HUDDLE_REAPER_INTERVAL", time.Minute)
+	go NewReaper(huddleStore, reaperInterval).Run(context.Background())
+
+	// Chat hub stays in sync with huddle lifecycle across replicas via the
+	// store's event stream (e.g. a huddle ended on another replica).
+	chatHub = NewChatHub()
+	go chatHub.WatchStore(context.Background(), huddleStore)
+
+	// Reconcile in-progress cloud recordings against Agora's own view so a
+	// restart mid-recording doesn't leave stale "started" state behind.
+	reconcilerInterval := envDuration("RECORDING_RECONCILE_INTERVAL", 30*time.Second)
+	go NewRecordingReconciler(huddleStore, reconcilerInterval).Run(context.Background())
+
+	// Keep huddle/participant gauges and counters in sync across replicas
+	// the same way chatHub does, and deliver webhook events for anyone
+	// who registered one.
+	go WatchStoreMetrics(context.Background(), huddleStore)
+	webhookDispatcher = NewWebhookDispatcher(webhookRegistryFromEnv())
+	go webhookDispatcher.WatchStore(context.Background(), huddleStore)
 
 	api := gin.Default()
 
@@ -235,21 +97,43 @@ func main() {
 		})
 	})
 
-	// Token endpoints with automatic huddle management
-	api.GET("rtc/:channelName/:role/:tokenType/:uid", getRtcToken)
-	api.GET("rtm/:uid/", getRtmToken)
-	api.GET("rte/:channelName/:role/:tokenType/:uid/", getBothRokens)
+	// Prometheus scrape endpoint
+	api.GET("/metrics", gin.WrapH(promhttp.Handler()))
+
+	// Outbound webhook subscriptions
+	api.POST("/webhooks", RequireOperator(), registerWebhook)
+	api.GET("/webhooks", RequireOperator(), listWebhooks)
+
+	// Token endpoints with automatic huddle management. These require auth
+	// so the uid a token is issued for is the caller's own authenticated
+	// identity, not whatever string they put in the URL.
+	api.GET("rtc/:channelName/:role/:tokenType/:uid", RequireAuth(), getRtcToken)
+	api.GET("rtm/:uid/", RequireAuth(), getRtmToken)
+	api.GET("rte/:channelName/:role/:tokenType/:uid/", RequireAuth(), getBothRokens)
 
 	// Dedicated huddle management endpoints
-	api.POST("/huddle/create", createHuddle)           // Explicitly create a huddle
-	api.POST("/huddle/join", joinHuddle)               // Explicitly join a huddle
-	api.POST("/huddle/leave", leaveHuddle)             // Leave a huddle
-	api.POST("/huddle/end", endHuddleByID)             // End huddle by ID
-	api.GET("/huddle/list", listHuddles)               // List all huddles
+	api.POST("/huddle/create", RequireAuth(), createHuddle)                 // Explicitly create a huddle
+	api.POST("/huddle/join", RequireAuth(), joinHuddle)                     // Explicitly join a huddle
+	api.POST("/huddle/leave", RequireAuth(), leaveHuddle)                   // Leave a huddle
+	api.POST("/huddle/end", RequireAuth(), endHuddleByID)                   // End huddle by ID
+	api.POST("/huddle/:id/extend", RequireAuth(), extendHuddle)             // Push out a huddle's expiry
+	api.POST("/huddle/:id/chat-token", RequireAuth(), getChatToken)         // Issue a short-lived chat WebSocket token
+	api.GET("/huddle/:id/ws", huddleChatWebSocket)                          // Chat/danmaku WebSocket
+	api.POST("/huddle/:id/recording/start", RequireAuth(), startRecording)  // Start Agora Cloud Recording
+	api.POST("/huddle/:id/recording/stop", RequireAuth(), stopRecording)    // Stop Agora Cloud Recording
+	api.GET("/huddle/:id/recording/status", RequireAuth(), recordingStatus) // Current recording state
+	api.GET("/huddle/list", listHuddles)                                    // List all huddles
+
+	// Moderator endpoints - only a co-host or host may change another
+	// user's standing in the huddle.
+	api.POST("/huddle/:id/role", RequireAuth(), RequireHuddleRole(RoleCoHost), setHuddleRole)
+	api.POST("/huddle/:id/kick", RequireAuth(), RequireHuddleRole(RoleCoHost), kickHuddleUser)
+	api.POST("/huddle/:id/mute", RequireAuth(), RequireHuddleRole(RoleCoHost), muteHuddleUser)
+	api.POST("/huddle/:id/ban", RequireAuth(), RequireHuddleRole(RoleCoHost), banHuddleUser)
 
 	// Alternative huddle query endpoints
-	api.GET("/huddles", listHuddles)                   // List all active huddles
-	api.DELETE("/huddles/:channelName", endHuddleByChannel) // End by channel name
+	api.GET("/huddles", listHuddles)                                       // List all active huddles
+	api.DELETE("/huddles/:channelName", RequireAuth(), endHuddleByChannel) // End by channel name
 
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -263,17 +147,14 @@ func main() {
 // Dedicated Huddle Management Handlers
 
 func createHuddle(c *gin.Context) {
-	var req struct {
-		CreatedBy string `json:"created_by" binding:"required"`
-	}
-
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"error": "created_by is required"})
+	// created_by comes from the verified auth token, not the request body -
+	// otherwise any caller could create huddles "owned" by someone else.
+	huddle, err := huddleStore.Create(c.Request.Context(), authUserID(c))
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
 		return
 	}
 
-	huddle := huddleStore.Create(req.CreatedBy)
-
 	c.JSON(201, gin.H{
 		"huddle_id":    huddle.ID,
 		"channel_name": huddle.ChannelName,
@@ -286,15 +167,14 @@ func createHuddle(c *gin.Context) {
 func joinHuddle(c *gin.Context) {
 	var req struct {
 		HuddleID string `json:"huddle_id" binding:"required"`
-		UserID   string `json:"user_id" binding:"required"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"error": "huddle_id and user_id are required"})
+		c.JSON(400, gin.H{"error": "huddle_id is required"})
 		return
 	}
 
-	if err := huddleStore.Join(req.HuddleID, req.UserID); err != nil {
+	if err := huddleStore.Join(c.Request.Context(), req.HuddleID, authUserID(c)); err != nil {
 		c.JSON(400, gin.H{"error": err.Error()})
 		return
 	}
@@ -305,15 +185,14 @@ func joinHuddle(c *gin.Context) {
 func leaveHuddle(c *gin.Context) {
 	var req struct {
 		HuddleID string `json:"huddle_id" binding:"required"`
-		UserID   string `json:"user_id" binding:"required"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"error": "huddle_id and user_id are required"})
+		c.JSON(400, gin.H{"error": "huddle_id is required"})
 		return
 	}
 
-	if err := huddleStore.Leave(req.HuddleID, req.UserID); err != nil {
+	if err := huddleStore.Leave(c.Request.Context(), req.HuddleID, authUserID(c)); err != nil {
 		c.JSON(400, gin.H{"error": err.Error()})
 		return
 	}
@@ -331,7 +210,7 @@ func endHuddleByID(c *gin.Context) {
 		return
 	}
 
-	if err := huddleStore.End(req.HuddleID); err != nil {
+	if err := huddleStore.End(c.Request.Context(), req.HuddleID); err != nil {
 		c.JSON(400, gin.H{"error": err.Error()})
 		return
 	}
@@ -339,10 +218,40 @@ func endHuddleByID(c *gin.Context) {
 	c.JSON(200, gin.H{"message": "Huddle ended successfully"})
 }
 
+func extendHuddle(c *gin.Context) {
+	huddleID := c.Param("id")
+
+	var req struct {
+		ExtendBy string `json:"extend_by"` // e.g. "15m"; defaults to HUDDLE_MAX_IDLE_DURATION
+	}
+	_ = c.ShouldBindJSON(&req)
+
+	by := envDuration("HUDDLE_MAX_IDLE_DURATION", 15*time.Minute)
+	if req.ExtendBy != "" {
+		parsed, err := time.ParseDuration(req.ExtendBy)
+		if err != nil {
+			c.JSON(400, gin.H{"error": "extend_by must be a valid duration, e.g. \"15m\""})
+			return
+		}
+		by = parsed
+	}
+
+	huddle, err := huddleStore.Extend(c.Request.Context(), huddleID, by)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"huddle_id":  huddle.ID,
+		"expires_at": huddle.ExpiresAt.Format(time.RFC3339),
+	})
+}
+
 func endHuddleByChannel(c *gin.Context) {
 	channelName := c.Param("channelName")
 
-	if err := huddleStore.EndByChannel(channelName); err != nil {
+	if err := huddleStore.EndByChannel(c.Request.Context(), channelName); err != nil {
 		c.JSON(404, gin.H{"error": "Huddle not found"})
 		return
 	}
@@ -351,7 +260,11 @@ func endHuddleByChannel(c *gin.Context) {
 }
 
 func listHuddles(c *gin.Context) {
-	huddles := huddleStore.List()
+	huddles, err := huddleStore.List(c.Request.Context())
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
 
 	response := make([]gin.H, 0, len(huddles))
 	for _, huddle := range huddles {
@@ -360,6 +273,8 @@ func listHuddles(c *gin.Context) {
 			"channel_name":      huddle.ChannelName,
 			"created_by":        huddle.CreatedBy,
 			"created_at":        huddle.CreatedAt.Format(time.RFC3339),
+			"expires_at":        huddle.ExpiresAt.Format(time.RFC3339),
+			"last_activity_at":  huddle.LastActivityAt.Format(time.RFC3339),
 			"participant_count": len(huddle.Participants),
 			"participants":      huddle.Participants,
 		})
@@ -381,12 +296,42 @@ func getRtcToken(c *gin.Context) {
 			})
 		return
 	}
+	// The uid a token is issued for is always the caller's own
+	// authenticated identity - the :uid path segment can't be used to
+	// request a token on someone else's behalf.
+	uidStr = authUserID(c)
+
+	if err := validateUIDForTokenType(tokenType, uidStr); err != nil {
+		c.Error(err)
+		c.AbortWithStatusJSON(400, gin.H{"status": 400, "message": "Error Generating RTC token: " + err.Error()})
+		return
+	}
 
 	// Automatically create or get huddle
-	huddle := huddleStore.GetOrCreate(channelName, uidStr)
+	huddle, err := huddleStore.GetOrCreate(c.Request.Context(), channelName, uidStr)
+	if err != nil {
+		c.Error(err)
+		c.AbortWithStatusJSON(500, gin.H{"status": 500, "message": "Error managing huddle: " + err.Error()})
+		return
+	}
+
+	switch roleOf(huddle, uidStr) {
+	case RoleBanned:
+		c.AbortWithStatusJSON(403, gin.H{"status": 403, "message": "banned from this huddle"})
+		return
+	case RoleMuted:
+		role = rtctokenbuilder2.RoleSubscriber
+	}
 
 	// Track participant joining
-	huddleStore.JoinByChannel(channelName, uidStr)
+	if err := huddleStore.JoinByChannel(c.Request.Context(), channelName, uidStr); err != nil {
+		c.Error(err)
+		c.AbortWithStatusJSON(500, gin.H{"status": 500, "message": "Error managing huddle: " + err.Error()})
+		return
+	}
+
+	// RTC tokens may never outlive the huddle they're scoped to.
+	expireTimestamp = capExpireTimestamp(expireTimestamp, huddle)
 
 	// generate the token
 	rtcToken, tokenErr := generateRtcToken(channelName, uidStr, tokenType, role, expireTimestamp)
@@ -418,6 +363,8 @@ func getRtmToken(c *gin.Context) {
 		})
 		return
 	}
+	// RTM tokens are issued for the caller's own authenticated identity.
+	uidStr = authUserID(c)
 	// build rtm token
 	rtmToken, tokenErr := rtmtokenbuilder2.BuildToken(appID, appCertificate, uidStr, expireTimestamp, "")
 	// return rtm token
@@ -448,11 +395,41 @@ func getBothRokens(c *gin.Context) {
 		return
 	}
 
+	// The uid tokens are issued for is always the caller's own
+	// authenticated identity, same as getRtcToken.
+	uidStr = authUserID(c)
+
+	if err := validateUIDForTokenType(tokenType, uidStr); err != nil {
+		c.Error(err)
+		c.AbortWithStatusJSON(400, gin.H{"status": 400, "message": "Error Generating RTC token params: " + err.Error()})
+		return
+	}
+
 	// Automatically create or get huddle
-	huddle := huddleStore.GetOrCreate(channelName, uidStr)
+	huddle, err := huddleStore.GetOrCreate(c.Request.Context(), channelName, uidStr)
+	if err != nil {
+		c.Error(err)
+		c.AbortWithStatusJSON(500, gin.H{"status": 500, "message": "Error managing huddle: " + err.Error()})
+		return
+	}
+
+	switch roleOf(huddle, uidStr) {
+	case RoleBanned:
+		c.AbortWithStatusJSON(403, gin.H{"status": 403, "message": "banned from this huddle"})
+		return
+	case RoleMuted:
+		role = rtctokenbuilder2.RoleSubscriber
+	}
 
 	// Track participant joining
-	huddleStore.JoinByChannel(channelName, uidStr)
+	if err := huddleStore.JoinByChannel(c.Request.Context(), channelName, uidStr); err != nil {
+		c.Error(err)
+		c.AbortWithStatusJSON(500, gin.H{"status": 500, "message": "Error managing huddle: " + err.Error()})
+		return
+	}
+
+	// RTC/RTM tokens may never outlive the huddle they're scoped to.
+	expireTimestamp = capExpireTimestamp(expireTimestamp, huddle)
 
 	// generate rtc token
 	rtcToken, rtcTokenErr := generateRtcToken(channelName, uidStr, tokenType, role, expireTimestamp)
@@ -529,23 +506,54 @@ func parseRtmParams(c *gin.Context) (uidStr string, expireTimestamp uint32, err
 	return uidStr, expireTimestamp, err
 }
 
+// validateUIDForTokenType rejects tokenType=="uid" requests up front when
+// the authenticated user's ID isn't numeric, since Agora's native uid
+// tokens require a uint32 and Telex user IDs aren't guaranteed to be one.
+// Checking this before GetOrCreate/JoinByChannel avoids joining the caller
+// to a huddle for a token request that's going to fail anyway.
+func validateUIDForTokenType(tokenType, uidStr string) error {
+	if tokenType != "uid" {
+		return nil
+	}
+	if _, err := strconv.ParseUint(uidStr, 10, 64); err != nil {
+		return fmt.Errorf("tokenType=uid requires a numeric user id, got %q; use tokenType=userAccount instead", uidStr)
+	}
+	return nil
+}
+
+// capExpireTimestamp clamps a requested token expiry to the huddle's
+// ExpiresAt so a long-lived token can't outlive the room the reaper will
+// eventually tear down.
+func capExpireTimestamp(expireTimestamp uint32, huddle *Huddle) uint32 {
+	huddleExpiry := uint32(huddle.ExpiresAt.Unix())
+	if expireTimestamp > huddleExpiry {
+		return huddleExpiry
+	}
+	return expireTimestamp
+}
+
 func generateRtcToken(channelName, uidStr, tokenType string, role rtctokenbuilder2.Role, expireTimestamp uint32) (rtcToken string, err error) {
+	start := time.Now()
+	defer func() { tokenGenerationDuration.Observe(time.Since(start).Seconds()) }()
+
 	// check token type
 	if tokenType == "userAccount" {
 		rtcToken, err = rtctokenbuilder2.BuildTokenWithAccount(appID, appCertificate, channelName, uidStr, role, expireTimestamp)
-		return rtcToken, err
 	} else if tokenType == "uid" {
 		uid64, parseErr := strconv.ParseUint(uidStr, 10, 64)
 		if parseErr != nil {
-			err = fmt.Errorf("Failed to parse uidStr: %s, to uint causing error: %s", uidStr, parseErr)
-			return "", err
+			return "", fmt.Errorf("Failed to parse uidStr: %s, to uint causing error: %s", uidStr, parseErr)
 		}
 		uid := uint32(uid64)
 		rtcToken, err = rtctokenbuilder2.BuildTokenWithUid(appID, appCertificate, channelName, uid, role, expireTimestamp)
-		return rtcToken, err
 	} else {
 		err = fmt.Errorf("failed to generate RTC token for unknown tokenType: %s", tokenType)
 		log.Println(err)
 		return "", err
 	}
+
+	if err == nil {
+		recordTokenIssued(role, tokenType)
+	}
+	return rtcToken, err
 }
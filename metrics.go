@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	rtctokenbuilder2 "github.com/AgoraIO-Community/go-tokenbuilder/rtctokenbuilder"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	huddlesCreatedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "huddles_created_total",
+		Help: "Total number of huddles created.",
+	})
+	huddlesEndedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "huddles_ended_total",
+		Help: "Total number of huddles ended.",
+	})
+	activeHuddles = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "active_huddles",
+		Help: "Current number of huddles that have been created but not yet ended.",
+	})
+	participantsPerHuddle = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "participants_per_huddle",
+		Help:    "Number of participants in a huddle at the time a participant joins or leaves.",
+		Buckets: []float64{0, 1, 2, 3, 5, 8, 13, 21, 34},
+	})
+	rtcTokensIssuedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "rtc_tokens_issued_total",
+		Help: "Total number of RTC tokens issued, by issued role and token type.",
+	}, []string{"role", "tokenType"})
+	tokenGenerationDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name: "token_generation_duration_seconds",
+		Help: "Time spent building an RTC token.",
+	})
+)
+
+// recordTokenIssued increments rtc_tokens_issued_total for a successfully
+// generated RTC token.
+func recordTokenIssued(role rtctokenbuilder2.Role, tokenType string) {
+	roleLabel := "subscriber"
+	if role == rtctokenbuilder2.RolePublisher {
+		roleLabel = "publisher"
+	}
+	rtcTokensIssuedTotal.WithLabelValues(roleLabel, tokenType).Inc()
+}
+
+// WatchStoreMetrics subscribes to the huddle store's event stream and
+// keeps huddles_created_total/huddles_ended_total/active_huddles/
+// participants_per_huddle up to date across every replica, the same way
+// chatHub.WatchStore keeps chat sockets in sync.
+func WatchStoreMetrics(ctx context.Context, store Store) {
+	events, err := store.Watch(ctx)
+	if err != nil {
+		log.Printf("metrics: watch store: %v", err)
+		return
+	}
+	for evt := range events {
+		switch evt.Type {
+		case EventHuddleCreated:
+			huddlesCreatedTotal.Inc()
+			activeHuddles.Inc()
+		case EventHuddleEnded:
+			huddlesEndedTotal.Inc()
+			activeHuddles.Dec()
+		case EventParticipantJoined, EventParticipantLeft:
+			if huddle, err := store.Get(ctx, evt.HuddleID); err == nil {
+				participantsPerHuddle.Observe(float64(len(huddle.Participants)))
+			}
+		}
+	}
+}
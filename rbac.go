@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Role is a user's standing within a huddle. It doubles as both a
+// moderation rank (host/co_host/participant) and a participation
+// restriction (muted/banned) - a user holds exactly one at a time, so
+// muting or banning someone overwrites whatever rank they held before.
+type Role string
+
+const (
+	RoleHost        Role = "host"
+	RoleCoHost      Role = "co_host"
+	RoleParticipant Role = "participant"
+	RoleMuted       Role = "muted"
+	RoleBanned      Role = "banned"
+)
+
+// roleRank orders roles from least to most privileged so
+// RequireHuddleRole can do a single >= comparison. Muted/banned rank
+// below an ordinary participant since they constrain rather than grant
+// privilege.
+var roleRank = map[Role]int{
+	RoleBanned:      -1,
+	RoleMuted:       0,
+	RoleParticipant: 1,
+	RoleCoHost:      2,
+	RoleHost:        3,
+}
+
+// roleOf returns a user's effective role in a huddle: whatever Roles
+// records explicitly, the creator defaulting to host, or participant.
+func roleOf(huddle *Huddle, userID string) Role {
+	if role, ok := huddle.Roles[userID]; ok {
+		return role
+	}
+	if huddle.CreatedBy == userID {
+		return RoleHost
+	}
+	return RoleParticipant
+}
+
+// requireRankBelowCaller rejects assigning or removing a role at or above
+// the caller's own rank in huddle, so a co-host can't self-promote to
+// host or act on a user who outranks or matches them (including the
+// actual host).
+func requireRankBelowCaller(huddle *Huddle, callerID string, rank int) error {
+	if rank >= roleRank[roleOf(huddle, callerID)] {
+		return fmt.Errorf("cannot assign or remove a role at or above your own")
+	}
+	return nil
+}
+
+// RequireHuddleRole aborts the request with 403 unless the authenticated
+// caller's role in the :id huddle ranks at or above minRole. It must be
+// chained after RequireAuth so authUserID(c) is populated.
+func RequireHuddleRole(minRole Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		huddle, err := huddleStore.Get(c.Request.Context(), c.Param("id"))
+		if err != nil {
+			c.AbortWithStatusJSON(404, gin.H{"error": "huddle not found"})
+			return
+		}
+
+		if roleRank[roleOf(huddle, authUserID(c))] < roleRank[minRole] {
+			c.AbortWithStatusJSON(403, gin.H{"error": "insufficient huddle role"})
+			return
+		}
+		c.Next()
+	}
+}
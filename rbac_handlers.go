@@ -0,0 +1,142 @@
+package main
+
+import "github.com/gin-gonic/gin"
+
+func validRole(role Role) bool {
+	_, ok := roleRank[role]
+	return ok
+}
+
+// setHuddleRole lets a host/co-host assign another user's role, e.g.
+// promoting a participant to co-host. Guarded by RequireHuddleRole(RoleCoHost);
+// the caller additionally can't assign a role at or above their own rank,
+// or touch a user who already outranks or matches them.
+func setHuddleRole(c *gin.Context) {
+	var req struct {
+		UserID string `json:"user_id" binding:"required"`
+		Role   Role   `json:"role" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || !validRole(req.Role) {
+		c.JSON(400, gin.H{"error": "user_id and a valid role are required"})
+		return
+	}
+
+	huddleID := c.Param("id")
+	huddle, err := huddleStore.Get(c.Request.Context(), huddleID)
+	if err != nil {
+		c.JSON(404, gin.H{"error": "huddle not found"})
+		return
+	}
+	if err := requireRankBelowCaller(huddle, authUserID(c), roleRank[req.Role]); err != nil {
+		c.JSON(403, gin.H{"error": err.Error()})
+		return
+	}
+	if err := requireRankBelowCaller(huddle, authUserID(c), roleRank[roleOf(huddle, req.UserID)]); err != nil {
+		c.JSON(403, gin.H{"error": err.Error()})
+		return
+	}
+
+	huddle, err = huddleStore.SetRole(c.Request.Context(), huddleID, req.UserID, req.Role)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{"huddle_id": huddle.ID, "user_id": req.UserID, "role": req.Role})
+}
+
+// kickHuddleUser removes a participant from the huddle without recording
+// any lasting role change - they're free to rejoin. Guarded by
+// RequireHuddleRole(RoleCoHost); the caller can't kick a user who already
+// outranks or matches them.
+func kickHuddleUser(c *gin.Context) {
+	var req struct {
+		UserID string `json:"user_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "user_id is required"})
+		return
+	}
+
+	huddleID := c.Param("id")
+	huddle, err := huddleStore.Get(c.Request.Context(), huddleID)
+	if err != nil {
+		c.JSON(404, gin.H{"error": "huddle not found"})
+		return
+	}
+	if err := requireRankBelowCaller(huddle, authUserID(c), roleRank[roleOf(huddle, req.UserID)]); err != nil {
+		c.JSON(403, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := huddleStore.Leave(c.Request.Context(), huddleID, req.UserID); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{"message": "user removed from huddle"})
+}
+
+// muteHuddleUser sets a participant's role to muted: they stay in the
+// huddle, but getRtcToken downgrades any token they request to
+// subscriber. Guarded by RequireHuddleRole(RoleCoHost); the caller can't
+// mute a user who already outranks or matches them.
+func muteHuddleUser(c *gin.Context) {
+	var req struct {
+		UserID string `json:"user_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "user_id is required"})
+		return
+	}
+
+	huddleID := c.Param("id")
+	huddle, err := huddleStore.Get(c.Request.Context(), huddleID)
+	if err != nil {
+		c.JSON(404, gin.H{"error": "huddle not found"})
+		return
+	}
+	if err := requireRankBelowCaller(huddle, authUserID(c), roleRank[roleOf(huddle, req.UserID)]); err != nil {
+		c.JSON(403, gin.H{"error": err.Error()})
+		return
+	}
+
+	huddle, err = huddleStore.SetRole(c.Request.Context(), huddleID, req.UserID, RoleMuted)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{"huddle_id": huddle.ID, "user_id": req.UserID, "role": RoleMuted})
+}
+
+// banHuddleUser sets a participant's role to banned and removes them from
+// the huddle; getRtcToken refuses to issue tokens to a banned user.
+// Guarded by RequireHuddleRole(RoleCoHost); the caller can't ban a user
+// who already outranks or matches them (so a co-host can't ban the host).
+func banHuddleUser(c *gin.Context) {
+	var req struct {
+		UserID string `json:"user_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "user_id is required"})
+		return
+	}
+
+	huddleID := c.Param("id")
+	huddle, err := huddleStore.Get(c.Request.Context(), huddleID)
+	if err != nil {
+		c.JSON(404, gin.H{"error": "huddle not found"})
+		return
+	}
+	if err := requireRankBelowCaller(huddle, authUserID(c), roleRank[roleOf(huddle, req.UserID)]); err != nil {
+		c.JSON(403, gin.H{"error": err.Error()})
+		return
+	}
+
+	huddle, err = huddleStore.SetRole(c.Request.Context(), huddleID, req.UserID, RoleBanned)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	_ = huddleStore.Leave(c.Request.Context(), huddleID, req.UserID) // already-left is fine
+
+	c.JSON(200, gin.H{"huddle_id": huddle.ID, "user_id": req.UserID, "role": RoleBanned})
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Reaper periodically scans the Store for huddles that have either sat
+// empty past their MaxIdleDuration or outlived their ExpiresAt, and ends
+// them. Without this, huddles created via GetOrCreate in getRtcToken
+// accumulate forever since nothing else ever calls End on abandoned
+// rooms.
+type Reaper struct {
+	store    Store
+	interval time.Duration
+}
+
+// NewReaper builds a Reaper that scans every interval.
+func NewReaper(store Store, interval time.Duration) *Reaper {
+	return &Reaper{store: store, interval: interval}
+}
+
+// Run blocks, scanning on every tick until ctx is cancelled.
+func (r *Reaper) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.sweep(ctx)
+		}
+	}
+}
+
+func (r *Reaper) sweep(ctx context.Context) {
+	huddles, err := r.store.List(ctx)
+	if err != nil {
+		log.Printf("reaper: list huddles: %v", err)
+		return
+	}
+
+	now := time.Now().UTC()
+	for _, huddle := range huddles {
+		reason := ""
+		switch {
+		case now.After(huddle.ExpiresAt):
+			reason = "expired"
+		case len(huddle.Participants) == 0 && now.Sub(huddle.LastActivityAt) > huddle.MaxIdleDuration:
+			reason = "idle"
+		default:
+			continue
+		}
+
+		if err := r.store.End(ctx, huddle.ID); err != nil {
+			log.Printf("reaper: end huddle %s (%s): %v", huddle.ID, reason, err)
+			continue
+		}
+		log.Printf("reaper: ended huddle %s (%s)", huddle.ID, reason)
+	}
+}
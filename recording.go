@@ -0,0 +1,159 @@
+package main
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const recordingMode = "mixed"
+
+// startRecording wraps Agora Cloud Recording's acquire -> start sequence
+// and stores the resulting resource/session IDs on the huddle so
+// stop/status and the reconciliation poller can find them later.
+func startRecording(c *gin.Context) {
+	huddleID := c.Param("id")
+
+	var req struct {
+		UID     string         `json:"uid" binding:"required"`
+		Storage *StorageConfig `json:"storage"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "uid is required"})
+		return
+	}
+
+	huddle, err := huddleStore.Get(c.Request.Context(), huddleID)
+	if err != nil {
+		c.JSON(404, gin.H{"error": "huddle not found"})
+		return
+	}
+	if huddle.Recording != nil && huddle.Recording.Status == "started" {
+		c.JSON(409, gin.H{"error": "recording already in progress"})
+		return
+	}
+
+	// AccessKey/SecretKey are never accepted from the request body (see
+	// StorageConfig) - only vendor/region/bucket/filename_prefix can be
+	// overridden per call, credentials always come from the env.
+	storage := storageConfigFromEnv()
+	if req.Storage != nil {
+		storage.Vendor = req.Storage.Vendor
+		storage.Region = req.Storage.Region
+		storage.Bucket = req.Storage.Bucket
+		storage.FilenamePrefix = req.Storage.FilenamePrefix
+	}
+
+	client, err := NewAgoraRecordingClient(appID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	resourceID, err := client.Acquire(c.Request.Context(), huddle.ChannelName, req.UID)
+	if err != nil {
+		c.JSON(502, gin.H{"error": "agora acquire failed: " + err.Error()})
+		return
+	}
+
+	sid, err := client.Start(c.Request.Context(), huddle.ChannelName, req.UID, resourceID, recordingMode, storage)
+	if err != nil {
+		c.JSON(502, gin.H{"error": "agora start failed: " + err.Error()})
+		return
+	}
+
+	recording := &RecordingState{
+		ResourceID: resourceID,
+		SID:        sid,
+		Mode:       recordingMode,
+		Storage:    storage,
+		Status:     "started",
+		StartedAt:  time.Now().UTC(),
+	}
+	huddle, err = huddleStore.SetRecording(c.Request.Context(), huddleID, recording)
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	chatHub.Broadcast(huddleID, ChatMessage{Type: ChatMessageRecording, UserID: req.UID, Body: "started", Timestamp: time.Now().UTC()})
+
+	c.JSON(200, gin.H{
+		"resource_id": resourceID,
+		"sid":         sid,
+		"status":      huddle.Recording.Status,
+	})
+}
+
+// stopRecording wraps Agora's stop call and records the final file list.
+func stopRecording(c *gin.Context) {
+	huddleID := c.Param("id")
+
+	var req struct {
+		UID string `json:"uid" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "uid is required"})
+		return
+	}
+
+	huddle, err := huddleStore.Get(c.Request.Context(), huddleID)
+	if err != nil {
+		c.JSON(404, gin.H{"error": "huddle not found"})
+		return
+	}
+	if huddle.Recording == nil || huddle.Recording.Status != "started" {
+		c.JSON(409, gin.H{"error": "no recording in progress"})
+		return
+	}
+
+	client, err := NewAgoraRecordingClient(appID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	files, err := client.Stop(c.Request.Context(), huddle.ChannelName, req.UID, huddle.Recording.ResourceID, huddle.Recording.SID, huddle.Recording.Mode)
+	if err != nil {
+		c.JSON(502, gin.H{"error": "agora stop failed: " + err.Error()})
+		return
+	}
+
+	stoppedAt := time.Now().UTC()
+	recording := *huddle.Recording
+	recording.Status = "stopped"
+	recording.StoppedAt = &stoppedAt
+	recording.Files = files
+
+	huddle, err = huddleStore.SetRecording(c.Request.Context(), huddleID, &recording)
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	chatHub.Broadcast(huddleID, ChatMessage{Type: ChatMessageRecording, UserID: req.UID, Body: "stopped", Timestamp: stoppedAt})
+
+	c.JSON(200, gin.H{
+		"status": huddle.Recording.Status,
+		"files":  huddle.Recording.Files,
+	})
+}
+
+// recordingStatus returns the last known recording state for a huddle.
+// The reconciliation poller (recording_poller.go) is what keeps this
+// fresh against Agora's own view rather than querying live on every call.
+func recordingStatus(c *gin.Context) {
+	huddleID := c.Param("id")
+
+	huddle, err := huddleStore.Get(c.Request.Context(), huddleID)
+	if err != nil {
+		c.JSON(404, gin.H{"error": "huddle not found"})
+		return
+	}
+	if huddle.Recording == nil {
+		c.JSON(200, gin.H{"status": "never_started"})
+		return
+	}
+
+	c.JSON(200, huddle.Recording)
+}
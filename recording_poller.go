@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// RecordingReconciler periodically re-queries Agora for every huddle this
+// process believes is still recording. This is what makes restart-safe: on
+// restart the in-progress RecordingState comes back from the Store (not
+// from process memory), but we don't actually know Agora's live view of it
+// until we ask.
+type RecordingReconciler struct {
+	store    Store
+	interval time.Duration
+}
+
+// NewRecordingReconciler builds a reconciler that scans every interval.
+func NewRecordingReconciler(store Store, interval time.Duration) *RecordingReconciler {
+	return &RecordingReconciler{store: store, interval: interval}
+}
+
+// Run blocks, reconciling on every tick until ctx is cancelled.
+func (r *RecordingReconciler) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.sweep(ctx)
+		}
+	}
+}
+
+func (r *RecordingReconciler) sweep(ctx context.Context) {
+	client, err := NewAgoraRecordingClient(appID)
+	if err != nil {
+		// Cloud recording isn't configured; nothing to reconcile.
+		return
+	}
+
+	huddles, err := r.store.List(ctx)
+	if err != nil {
+		log.Printf("recording reconciler: list huddles: %v", err)
+		return
+	}
+
+	for _, huddle := range huddles {
+		if huddle.Recording == nil || huddle.Recording.Status != "started" {
+			continue
+		}
+
+		status, files, err := client.Query(ctx, huddle.Recording.ResourceID, huddle.Recording.SID, huddle.Recording.Mode)
+		if err != nil {
+			log.Printf("recording reconciler: query huddle %s: %v", huddle.ID, err)
+			continue
+		}
+
+		// Agora reports recording module status 5 as "exited" - the
+		// stream stopped on its own (e.g. channel went empty) without a
+		// client ever calling /recording/stop.
+		if status == "5" {
+			stoppedAt := time.Now().UTC()
+			recording := *huddle.Recording
+			recording.Status = "stopped"
+			recording.StoppedAt = &stoppedAt
+			recording.Files = files
+			if _, err := r.store.SetRecording(ctx, huddle.ID, &recording); err != nil {
+				log.Printf("recording reconciler: update huddle %s: %v", huddle.ID, err)
+				continue
+			}
+			chatHub.Broadcast(huddle.ID, ChatMessage{Type: ChatMessageRecording, Body: "stopped", Timestamp: stoppedAt})
+			log.Printf("recording reconciler: huddle %s recording exited upstream", huddle.ID)
+		}
+	}
+}
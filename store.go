@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// newHuddleID generates the random ID used for new huddles across every
+// Store driver.
+func newHuddleID() string {
+	return uuid.New().String()
+}
+
+// Huddle represents a huddle room
+type Huddle struct {
+	ID           string    `json:"huddle_id"`
+	ChannelName  string    `json:"channel_name"`
+	CreatedBy    string    `json:"created_by"`
+	CreatedAt    time.Time `json:"created_at"`
+	Participants []string  `json:"participants"`
+
+	// ExpiresAt is the hard cutoff the reaper enforces regardless of
+	// activity; extend it via the /huddle/:id/extend endpoint. RTC tokens
+	// issued for this huddle may never expire later than this.
+	ExpiresAt time.Time `json:"expires_at"`
+	// LastActivityAt is bumped whenever Join/JoinByChannel adds a
+	// participant and is what the
+	// idle-expiry check in the reaper compares against MaxIdleDuration.
+	LastActivityAt time.Time `json:"last_activity_at"`
+	// MaxIdleDuration and MaxLifetime are captured at creation time so a
+	// later change to the env defaults doesn't retroactively affect
+	// huddles already in flight.
+	MaxIdleDuration time.Duration `json:"max_idle_duration"`
+	MaxLifetime     time.Duration `json:"max_lifetime"`
+
+	// Recording holds Agora Cloud Recording state for this huddle, or nil
+	// if recording has never been started.
+	Recording *RecordingState `json:"recording,omitempty"`
+
+	// Roles records moderation state per user (host, co_host, muted,
+	// banned). A user with no entry is an ordinary participant, or the
+	// host if they're CreatedBy - see roleOf in rbac.go.
+	Roles map[string]Role `json:"roles,omitempty"`
+}
+
+// cloneHuddle returns a copy of h with its own Participants backing array
+// and Roles map, so a caller holding the clone can read those fields
+// without racing a driver's internal mutations to the original - see
+// MemoryStore, the one driver that hands out pointers into live state
+// rather than a value freshly decoded per call.
+func cloneHuddle(h *Huddle) *Huddle {
+	clone := *h
+	if h.Participants != nil {
+		clone.Participants = append([]string(nil), h.Participants...)
+	}
+	if h.Roles != nil {
+		clone.Roles = make(map[string]Role, len(h.Roles))
+		for userID, role := range h.Roles {
+			clone.Roles[userID] = role
+		}
+	}
+	return &clone
+}
+
+// huddleLifecycleDefaults reads the configured idle/lifetime thresholds
+// used when a huddle is created. HUDDLE_MAX_IDLE_DURATION and
+// HUDDLE_MAX_LIFETIME are parsed with time.ParseDuration (e.g. "15m",
+// "4h"); invalid or unset values fall back to the defaults below.
+func huddleLifecycleDefaults() (maxIdle, maxLifetime time.Duration) {
+	maxIdle = envDuration("HUDDLE_MAX_IDLE_DURATION", 15*time.Minute)
+	maxLifetime = envDuration("HUDDLE_MAX_LIFETIME", 4*time.Hour)
+	return maxIdle, maxLifetime
+}
+
+// newHuddleLifecycle stamps the lifecycle fields for a huddle being
+// created now.
+func newHuddleLifecycle() (expiresAt, lastActivityAt time.Time, maxIdle, maxLifetime time.Duration) {
+	maxIdle, maxLifetime = huddleLifecycleDefaults()
+	now := time.Now().UTC()
+	return now.Add(maxLifetime), now, maxIdle, maxLifetime
+}
+
+// HuddleEventType identifies the kind of change carried by a HuddleEvent.
+type HuddleEventType string
+
+const (
+	EventHuddleCreated     HuddleEventType = "huddle.created"
+	EventHuddleEnded       HuddleEventType = "huddle.ended"
+	EventParticipantJoined HuddleEventType = "participant.joined"
+	EventParticipantLeft   HuddleEventType = "participant.left"
+	EventRecordingStarted  HuddleEventType = "recording.started"
+	EventRecordingStopped  HuddleEventType = "recording.stopped"
+)
+
+// recordingEvent builds the event a Store should emit after SetRecording,
+// based on the new state's Status - shared across drivers so "started" vs
+// "stopped" is classified the same way everywhere.
+func recordingEvent(huddleID, channelName string, recording *RecordingState) HuddleEvent {
+	evt := HuddleEvent{HuddleID: huddleID, ChannelName: channelName}
+	if recording != nil && recording.Status == "stopped" {
+		evt.Type = EventRecordingStopped
+	} else {
+		evt.Type = EventRecordingStarted
+	}
+	return evt
+}
+
+// HuddleEvent is emitted by a Store whenever huddle state changes, whether
+// the change originated locally or on a remote replica. It lets a process
+// react to state it didn't cause itself (e.g. another replica ending a
+// huddle behind a load balancer).
+type HuddleEvent struct {
+	Type        HuddleEventType `json:"type"`
+	HuddleID    string          `json:"huddle_id"`
+	ChannelName string          `json:"channel_name"`
+	UserID      string          `json:"user_id,omitempty"`
+	Timestamp   time.Time       `json:"timestamp"`
+}
+
+// Store manages huddle state and makes it visible across all replicas of
+// the service. Implementations must be safe for concurrent use.
+//
+// Every mutating method must be safe to call concurrently from multiple
+// replicas: two replicas calling GetOrCreate for the same channel at the
+// same time must converge on a single huddle, and a Join/Leave on one
+// replica must eventually be observed via Watch on the others.
+type Store interface {
+	Create(ctx context.Context, createdBy string) (*Huddle, error)
+	Get(ctx context.Context, huddleID string) (*Huddle, error)
+	GetByChannel(ctx context.Context, channelName string) (*Huddle, error)
+	List(ctx context.Context) ([]*Huddle, error)
+	Join(ctx context.Context, huddleID, userID string) error
+	Leave(ctx context.Context, huddleID, userID string) error
+	End(ctx context.Context, huddleID string) error
+	EndByChannel(ctx context.Context, channelName string) error
+	GetOrCreate(ctx context.Context, channelName, userID string) (*Huddle, error)
+	JoinByChannel(ctx context.Context, channelName, userID string) error
+
+	// Extend pushes a huddle's ExpiresAt out by the given duration and
+	// returns the updated huddle.
+	Extend(ctx context.Context, huddleID string, by time.Duration) (*Huddle, error)
+
+	// SetRecording replaces a huddle's Recording state wholesale and
+	// returns the updated huddle. Used for every recording state
+	// transition (started -> stopped) rather than one method
+	// per transition, since the shape of the update is identical.
+	SetRecording(ctx context.Context, huddleID string, recording *RecordingState) (*Huddle, error)
+
+	// SetRole assigns a user's moderation role within a huddle (host,
+	// co_host, muted, banned) and returns the updated huddle.
+	SetRole(ctx context.Context, huddleID, userID string, role Role) (*Huddle, error)
+
+	// Watch streams huddle events as they happen on any replica. The
+	// channel is closed when ctx is cancelled; closing the store does not
+	// close it in every driver.
+	Watch(ctx context.Context) (<-chan HuddleEvent, error)
+
+	// Close releases any connections held by the store (DB pools, etcd
+	// clients, etc). Safe to call on the in-memory store as a no-op.
+	Close() error
+}
+
+// NewStore builds a Store for the given driver name, read from
+// HUDDLE_STORE_DRIVER. Recognized drivers are "memory" (default), "etcd",
+// "postgres" and "redis".
+func NewStore(driver string) (Store, error) {
+	switch driver {
+	case "", "memory":
+		return NewMemoryStore(), nil
+	case "etcd":
+		return NewEtcdStore(EtcdConfigFromEnv())
+	case "postgres":
+		return NewPostgresStore(PostgresConfigFromEnv())
+	case "redis":
+		return NewRedisStore(RedisConfigFromEnv())
+	default:
+		return nil, fmt.Errorf("unknown HUDDLE_STORE_DRIVER: %q", driver)
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+)
+
+// storeFactories lists every driver under test. Drivers that need a live
+// backend only register themselves when the matching env var is set, so
+// `go test ./...` stays hermetic by default and CI can opt in with real
+// infrastructure.
+func storeFactories(t *testing.T) map[string]func() Store {
+	factories := map[string]func() Store{
+		"memory": func() Store { return NewMemoryStore() },
+	}
+
+	if os.Getenv("ETCD_ENDPOINTS") != "" {
+		factories["etcd"] = func() Store {
+			s, err := NewEtcdStore(EtcdConfigFromEnv())
+			if err != nil {
+				t.Fatalf("etcd: %v", err)
+			}
+			return s
+		}
+	}
+	if os.Getenv("POSTGRES_DSN") != "" {
+		factories["postgres"] = func() Store {
+			s, err := NewPostgresStore(PostgresConfigFromEnv())
+			if err != nil {
+				t.Fatalf("postgres: %v", err)
+			}
+			return s
+		}
+	}
+	if os.Getenv("REDIS_ADDR") != "" {
+		factories["redis"] = func() Store {
+			s, err := NewRedisStore(RedisConfigFromEnv())
+			if err != nil {
+				t.Fatalf("redis: %v", err)
+			}
+			return s
+		}
+	}
+	return factories
+}
+
+// TestStoreConformance runs the same behavioral assertions against every
+// registered driver so that a change to one backend can't silently drift
+// from the others.
+func TestStoreConformance(t *testing.T) {
+	for name, factory := range storeFactories(t) {
+		t.Run(name, func(t *testing.T) {
+			store := factory()
+			defer store.Close()
+			ctx := context.Background()
+
+			huddle, err := store.Create(ctx, "alice")
+			if err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+			if huddle.CreatedBy != "alice" {
+				t.Fatalf("CreatedBy = %q, want alice", huddle.CreatedBy)
+			}
+
+			got, err := store.Get(ctx, huddle.ID)
+			if err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+			if got.ID != huddle.ID {
+				t.Fatalf("Get returned %q, want %q", got.ID, huddle.ID)
+			}
+
+			if _, err := store.GetByChannel(ctx, huddle.ChannelName); err != nil {
+				t.Fatalf("GetByChannel: %v", err)
+			}
+
+			if err := store.Join(ctx, huddle.ID, "bob"); err != nil {
+				t.Fatalf("Join: %v", err)
+			}
+			got, _ = store.Get(ctx, huddle.ID)
+			if len(got.Participants) != 1 || got.Participants[0] != "bob" {
+				t.Fatalf("Participants = %v, want [bob]", got.Participants)
+			}
+
+			// Joining twice is a no-op, not a duplicate entry.
+			if err := store.Join(ctx, huddle.ID, "bob"); err != nil {
+				t.Fatalf("Join (duplicate): %v", err)
+			}
+			got, _ = store.Get(ctx, huddle.ID)
+			if len(got.Participants) != 1 {
+				t.Fatalf("Participants = %v, want exactly one entry", got.Participants)
+			}
+
+			if err := store.Leave(ctx, huddle.ID, "bob"); err != nil {
+				t.Fatalf("Leave: %v", err)
+			}
+			if err := store.Leave(ctx, huddle.ID, "bob"); err == nil {
+				t.Fatalf("Leave (not a member) should error")
+			}
+
+			channel2 := huddle.ChannelName + "-other"
+			gotOrCreated, err := store.GetOrCreate(ctx, channel2, "carol")
+			if err != nil {
+				t.Fatalf("GetOrCreate: %v", err)
+			}
+			again, err := store.GetOrCreate(ctx, channel2, "dave")
+			if err != nil {
+				t.Fatalf("GetOrCreate (existing): %v", err)
+			}
+			if again.ID != gotOrCreated.ID {
+				t.Fatalf("GetOrCreate returned a second huddle for the same channel")
+			}
+
+			if err := store.JoinByChannel(ctx, channel2, "dave"); err != nil {
+				t.Fatalf("JoinByChannel: %v", err)
+			}
+
+			huddles, err := store.List(ctx)
+			if err != nil {
+				t.Fatalf("List: %v", err)
+			}
+			if len(huddles) < 2 {
+				t.Fatalf("List returned %d huddles, want at least 2", len(huddles))
+			}
+
+			withRole, err := store.SetRole(ctx, huddle.ID, "carol", RoleCoHost)
+			if err != nil {
+				t.Fatalf("SetRole: %v", err)
+			}
+			if withRole.Roles["carol"] != RoleCoHost {
+				t.Fatalf("Roles[carol] = %q, want %q", withRole.Roles["carol"], RoleCoHost)
+			}
+
+			before := gotOrCreated.ExpiresAt
+			extended, err := store.Extend(ctx, gotOrCreated.ID, time.Hour)
+			if err != nil {
+				t.Fatalf("Extend: %v", err)
+			}
+			if !extended.ExpiresAt.After(before) {
+				t.Fatalf("Extend did not push ExpiresAt forward: before=%v after=%v", before, extended.ExpiresAt)
+			}
+
+			if err := store.EndByChannel(ctx, channel2); err != nil {
+				t.Fatalf("EndByChannel: %v", err)
+			}
+			if err := store.End(ctx, huddle.ID); err != nil {
+				t.Fatalf("End: %v", err)
+			}
+			if _, err := store.Get(ctx, huddle.ID); err == nil {
+				t.Fatalf("Get after End should error")
+			}
+		})
+	}
+}
+
+func TestStoreConformanceWatch(t *testing.T) {
+	for name, factory := range storeFactories(t) {
+		t.Run(name, func(t *testing.T) {
+			store := factory()
+			defer store.Close()
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			events, err := store.Watch(ctx)
+			if err != nil {
+				t.Fatalf("Watch: %v", err)
+			}
+
+			huddle, err := store.Create(ctx, "alice")
+			if err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+
+			select {
+			case evt := <-events:
+				if evt.Type != EventHuddleCreated || evt.HuddleID != huddle.ID {
+					t.Fatalf("unexpected event %+v", evt)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("timed out waiting for huddle.created event")
+			}
+		})
+	}
+}
@@ -0,0 +1,356 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// EtcdConfig configures the etcd-backed Store.
+type EtcdConfig struct {
+	Endpoints   []string
+	DialTimeout time.Duration
+	KeyPrefix   string
+}
+
+// EtcdConfigFromEnv reads etcd connection settings from the environment.
+// ETCD_ENDPOINTS is a comma-separated list of host:port pairs.
+func EtcdConfigFromEnv() EtcdConfig {
+	endpoints := strings.Split(os.Getenv("ETCD_ENDPOINTS"), ",")
+	prefix := os.Getenv("ETCD_KEY_PREFIX")
+	if prefix == "" {
+		prefix = "/telex_agora/huddles/"
+	}
+	return EtcdConfig{
+		Endpoints:   endpoints,
+		DialTimeout: 5 * time.Second,
+		KeyPrefix:   prefix,
+	}
+}
+
+// EtcdStore stores huddles as JSON values under KeyPrefix+huddleID, and
+// relies on etcd's watch API to fan out changes to every replica without
+// any polling.
+type EtcdStore struct {
+	client        *clientv3.Client
+	prefix        string
+	channelPrefix string
+}
+
+// NewEtcdStore dials etcd and returns a ready-to-use Store.
+func NewEtcdStore(cfg EtcdConfig) (*EtcdStore, error) {
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints:   cfg.Endpoints,
+		DialTimeout: cfg.DialTimeout,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("etcd: dial: %w", err)
+	}
+	return &EtcdStore{
+		client: client,
+		prefix: cfg.KeyPrefix,
+		// channelPrefix deliberately lives outside prefix (it doesn't end in
+		// "/" before the suffix) so List's prefix scan over s.prefix never
+		// picks up an index entry and tries to unmarshal it as an etcdRecord.
+		channelPrefix: strings.TrimSuffix(cfg.KeyPrefix, "/") + "-by-channel/",
+	}, nil
+}
+
+func (s *EtcdStore) key(huddleID string) string {
+	return s.prefix + huddleID
+}
+
+// channelIndexKey maps a channel name to the huddle ID that owns it, used
+// only to make GetOrCreate's create path atomic across replicas - see
+// GetOrCreate.
+func (s *EtcdStore) channelIndexKey(channelName string) string {
+	return s.channelPrefix + channelName
+}
+
+// etcdRecord is what's actually stored at each huddle's key. Watch only
+// sees etcd's raw PUT/DELETE stream, so put annotates every write with the
+// HuddleEventType it represents - the same per-operation event each
+// MemoryStore method emits directly - letting Watch reconstruct it without
+// guessing from a value diff. LastEvent is left empty for mutations that
+// don't have a HuddleEvent of their own (SetRole, Extend), matching
+// MemoryStore, which doesn't emit for those either.
+type etcdRecord struct {
+	Huddle
+	LastEvent       HuddleEventType `json:"last_event,omitempty"`
+	LastEventUserID string          `json:"last_event_user,omitempty"`
+}
+
+func (s *EtcdStore) put(ctx context.Context, huddle *Huddle, evtType HuddleEventType, userID string) error {
+	data, err := json.Marshal(etcdRecord{Huddle: *huddle, LastEvent: evtType, LastEventUserID: userID})
+	if err != nil {
+		return fmt.Errorf("etcd: marshal huddle: %w", err)
+	}
+	_, err = s.client.Put(ctx, s.key(huddle.ID), string(data))
+	if err != nil {
+		return fmt.Errorf("etcd: put huddle: %w", err)
+	}
+	return nil
+}
+
+func (s *EtcdStore) Create(ctx context.Context, createdBy string) (*Huddle, error) {
+	expiresAt, lastActivityAt, maxIdle, maxLifetime := newHuddleLifecycle()
+	huddle := &Huddle{
+		ID:              newHuddleID(),
+		CreatedBy:       createdBy,
+		CreatedAt:       lastActivityAt,
+		Participants:    []string{},
+		ExpiresAt:       expiresAt,
+		LastActivityAt:  lastActivityAt,
+		MaxIdleDuration: maxIdle,
+		MaxLifetime:     maxLifetime,
+	}
+	huddle.ChannelName = fmt.Sprintf("huddle_%s", huddle.ID[:8])
+	if err := s.put(ctx, huddle, EventHuddleCreated, createdBy); err != nil {
+		return nil, err
+	}
+	return huddle, nil
+}
+
+func (s *EtcdStore) Get(ctx context.Context, huddleID string) (*Huddle, error) {
+	resp, err := s.client.Get(ctx, s.key(huddleID))
+	if err != nil {
+		return nil, fmt.Errorf("etcd: get huddle: %w", err)
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, fmt.Errorf("huddle not found")
+	}
+	var record etcdRecord
+	if err := json.Unmarshal(resp.Kvs[0].Value, &record); err != nil {
+		return nil, fmt.Errorf("etcd: unmarshal huddle: %w", err)
+	}
+	return &record.Huddle, nil
+}
+
+func (s *EtcdStore) GetByChannel(ctx context.Context, channelName string) (*Huddle, error) {
+	huddles, err := s.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range huddles {
+		if h.ChannelName == channelName {
+			return h, nil
+		}
+	}
+	return nil, fmt.Errorf("huddle not found")
+}
+
+func (s *EtcdStore) List(ctx context.Context) ([]*Huddle, error) {
+	resp, err := s.client.Get(ctx, s.prefix, clientv3.WithPrefix())
+	if err != nil {
+		return nil, fmt.Errorf("etcd: list huddles: %w", err)
+	}
+	huddles := make([]*Huddle, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		var record etcdRecord
+		if err := json.Unmarshal(kv.Value, &record); err != nil {
+			return nil, fmt.Errorf("etcd: unmarshal huddle: %w", err)
+		}
+		huddles = append(huddles, &record.Huddle)
+	}
+	return huddles, nil
+}
+
+func (s *EtcdStore) Join(ctx context.Context, huddleID, userID string) error {
+	huddle, err := s.Get(ctx, huddleID)
+	if err != nil {
+		return err
+	}
+	for _, p := range huddle.Participants {
+		if p == userID {
+			return nil
+		}
+	}
+	huddle.Participants = append(huddle.Participants, userID)
+	huddle.LastActivityAt = time.Now().UTC()
+	return s.put(ctx, huddle, EventParticipantJoined, userID)
+}
+
+func (s *EtcdStore) Leave(ctx context.Context, huddleID, userID string) error {
+	huddle, err := s.Get(ctx, huddleID)
+	if err != nil {
+		return err
+	}
+	for i, p := range huddle.Participants {
+		if p == userID {
+			huddle.Participants = append(huddle.Participants[:i], huddle.Participants[i+1:]...)
+			return s.put(ctx, huddle, EventParticipantLeft, userID)
+		}
+	}
+	return fmt.Errorf("user not in huddle")
+}
+
+func (s *EtcdStore) End(ctx context.Context, huddleID string) error {
+	huddle, err := s.Get(ctx, huddleID)
+	if err != nil {
+		return err
+	}
+	_, err = s.client.Txn(ctx).Then(
+		clientv3.OpDelete(s.key(huddleID)),
+		clientv3.OpDelete(s.channelIndexKey(huddle.ChannelName)),
+	).Commit()
+	if err != nil {
+		return fmt.Errorf("etcd: delete huddle: %w", err)
+	}
+	return nil
+}
+
+func (s *EtcdStore) EndByChannel(ctx context.Context, channelName string) error {
+	huddle, err := s.GetByChannel(ctx, channelName)
+	if err != nil {
+		return err
+	}
+	return s.End(ctx, huddle.ID)
+}
+
+// GetOrCreate must converge on a single huddle even when two replicas race
+// for the same channel - see the Store interface doc. The plain
+// GetByChannel check below can miss on both replicas, so the create itself
+// goes through a txn keyed on channelIndexKey that only succeeds for
+// whichever replica gets there first; the loser reads the winner's huddle
+// ID back out of the same response instead of creating a second huddle.
+func (s *EtcdStore) GetOrCreate(ctx context.Context, channelName, userID string) (*Huddle, error) {
+	if huddle, err := s.GetByChannel(ctx, channelName); err == nil {
+		return huddle, nil
+	}
+	expiresAt, lastActivityAt, maxIdle, maxLifetime := newHuddleLifecycle()
+	huddle := &Huddle{
+		ID:              newHuddleID(),
+		ChannelName:     channelName,
+		CreatedBy:       userID,
+		CreatedAt:       lastActivityAt,
+		Participants:    []string{},
+		ExpiresAt:       expiresAt,
+		LastActivityAt:  lastActivityAt,
+		MaxIdleDuration: maxIdle,
+		MaxLifetime:     maxLifetime,
+	}
+	data, err := json.Marshal(etcdRecord{Huddle: *huddle, LastEvent: EventHuddleCreated, LastEventUserID: userID})
+	if err != nil {
+		return nil, fmt.Errorf("etcd: marshal huddle: %w", err)
+	}
+
+	indexKey := s.channelIndexKey(channelName)
+	resp, err := s.client.Txn(ctx).
+		If(clientv3.Compare(clientv3.CreateRevision(indexKey), "=", 0)).
+		Then(clientv3.OpPut(indexKey, huddle.ID), clientv3.OpPut(s.key(huddle.ID), string(data))).
+		Else(clientv3.OpGet(indexKey)).
+		Commit()
+	if err != nil {
+		return nil, fmt.Errorf("etcd: get or create huddle: %w", err)
+	}
+	if resp.Succeeded {
+		return huddle, nil
+	}
+
+	// Lost the race: another replica's Then branch already ran. Read the
+	// huddle ID it wrote instead of creating a second huddle for this channel.
+	kvs := resp.Responses[0].GetResponseRange().Kvs
+	if len(kvs) == 0 {
+		return nil, fmt.Errorf("etcd: get or create huddle: channel index vanished")
+	}
+	return s.Get(ctx, string(kvs[0].Value))
+}
+
+func (s *EtcdStore) JoinByChannel(ctx context.Context, channelName, userID string) error {
+	huddle, err := s.GetByChannel(ctx, channelName)
+	if err != nil {
+		return err
+	}
+	return s.Join(ctx, huddle.ID, userID)
+}
+
+func (s *EtcdStore) SetRecording(ctx context.Context, huddleID string, recording *RecordingState) (*Huddle, error) {
+	huddle, err := s.Get(ctx, huddleID)
+	if err != nil {
+		return nil, err
+	}
+	huddle.Recording = recording
+	evt := recordingEvent(huddleID, huddle.ChannelName, recording)
+	if err := s.put(ctx, huddle, evt.Type, ""); err != nil {
+		return nil, err
+	}
+	return huddle, nil
+}
+
+func (s *EtcdStore) SetRole(ctx context.Context, huddleID, userID string, role Role) (*Huddle, error) {
+	huddle, err := s.Get(ctx, huddleID)
+	if err != nil {
+		return nil, err
+	}
+	if huddle.Roles == nil {
+		huddle.Roles = make(map[string]Role)
+	}
+	huddle.Roles[userID] = role
+	// No HuddleEvent for role changes, matching MemoryStore.SetRole.
+	if err := s.put(ctx, huddle, "", ""); err != nil {
+		return nil, err
+	}
+	return huddle, nil
+}
+
+func (s *EtcdStore) Extend(ctx context.Context, huddleID string, by time.Duration) (*Huddle, error) {
+	huddle, err := s.Get(ctx, huddleID)
+	if err != nil {
+		return nil, err
+	}
+	huddle.ExpiresAt = huddle.ExpiresAt.Add(by)
+	// No HuddleEvent for extensions, matching MemoryStore.Extend.
+	if err := s.put(ctx, huddle, "", ""); err != nil {
+		return nil, err
+	}
+	return huddle, nil
+}
+
+// Watch streams create/update/delete events on the huddle key prefix as a
+// single etcd watch, translating etcd's raw key events into HuddleEvents.
+func (s *EtcdStore) Watch(ctx context.Context) (<-chan HuddleEvent, error) {
+	out := make(chan HuddleEvent, 32)
+	watchCh := s.client.Watch(ctx, s.prefix, clientv3.WithPrefix())
+
+	go func() {
+		defer close(out)
+		for resp := range watchCh {
+			for _, ev := range resp.Events {
+				var record etcdRecord
+				evt := HuddleEvent{Timestamp: time.Now().UTC()}
+				if ev.Type == clientv3.EventTypeDelete {
+					evt.Type = EventHuddleEnded
+					evt.HuddleID = strings.TrimPrefix(string(ev.Kv.Key), s.prefix)
+				} else if json.Unmarshal(ev.Kv.Value, &record) == nil {
+					if record.LastEvent == "" {
+						// SetRole/Extend don't carry a HuddleEvent of their
+						// own; skip instead of misreporting them as huddle.created.
+						continue
+					}
+					evt.Type = record.LastEvent
+					evt.HuddleID = record.ID
+					evt.ChannelName = record.ChannelName
+					evt.UserID = record.LastEventUserID
+				} else {
+					continue
+				}
+				select {
+				case out <- evt:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+func (s *EtcdStore) Close() error {
+	return s.client.Close()
+}
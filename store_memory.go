@@ -0,0 +1,310 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MemoryStore is the in-memory Store implementation. It keeps no state
+// across restarts and does not coordinate with other replicas, but it is
+// the simplest driver to run locally and is what HUDDLE_STORE_DRIVER
+// defaults to.
+type MemoryStore struct {
+	mu      sync.RWMutex
+	huddles map[string]*Huddle
+
+	subMu sync.Mutex
+	subs  map[chan HuddleEvent]struct{}
+}
+
+// NewMemoryStore creates a new in-memory huddle store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		huddles: make(map[string]*Huddle),
+		subs:    make(map[chan HuddleEvent]struct{}),
+	}
+}
+
+func (s *MemoryStore) emit(evt HuddleEvent) {
+	evt.Timestamp = time.Now().UTC()
+	s.subMu.Lock()
+	defer s.subMu.Unlock()
+	for ch := range s.subs {
+		select {
+		case ch <- evt:
+		default:
+			// Slow subscriber; drop the event rather than block writers.
+		}
+	}
+}
+
+func (s *MemoryStore) Watch(ctx context.Context) (<-chan HuddleEvent, error) {
+	ch := make(chan HuddleEvent, 32)
+	s.subMu.Lock()
+	s.subs[ch] = struct{}{}
+	s.subMu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		s.subMu.Lock()
+		delete(s.subs, ch)
+		s.subMu.Unlock()
+		close(ch)
+	}()
+
+	return ch, nil
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+func (s *MemoryStore) SetRecording(ctx context.Context, huddleID string, recording *RecordingState) (*Huddle, error) {
+	s.mu.Lock()
+	huddle, exists := s.huddles[huddleID]
+	if !exists {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("huddle not found")
+	}
+	huddle.Recording = recording
+	channelName := huddle.ChannelName
+	clone := cloneHuddle(huddle)
+	s.mu.Unlock()
+
+	s.emit(recordingEvent(huddleID, channelName, recording))
+	return clone, nil
+}
+
+func (s *MemoryStore) SetRole(ctx context.Context, huddleID, userID string, role Role) (*Huddle, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	huddle, exists := s.huddles[huddleID]
+	if !exists {
+		return nil, fmt.Errorf("huddle not found")
+	}
+	if huddle.Roles == nil {
+		huddle.Roles = make(map[string]Role)
+	}
+	huddle.Roles[userID] = role
+	return cloneHuddle(huddle), nil
+}
+
+func (s *MemoryStore) Extend(ctx context.Context, huddleID string, by time.Duration) (*Huddle, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	huddle, exists := s.huddles[huddleID]
+	if !exists {
+		return nil, fmt.Errorf("huddle not found")
+	}
+	huddle.ExpiresAt = huddle.ExpiresAt.Add(by)
+	return cloneHuddle(huddle), nil
+}
+
+func (s *MemoryStore) Create(ctx context.Context, createdBy string) (*Huddle, error) {
+	expiresAt, lastActivityAt, maxIdle, maxLifetime := newHuddleLifecycle()
+
+	s.mu.Lock()
+	huddleID := uuid.New().String()
+	huddle := &Huddle{
+		ID:              huddleID,
+		ChannelName:     fmt.Sprintf("huddle_%s", huddleID[:8]),
+		CreatedBy:       createdBy,
+		CreatedAt:       lastActivityAt,
+		Participants:    []string{},
+		ExpiresAt:       expiresAt,
+		LastActivityAt:  lastActivityAt,
+		MaxIdleDuration: maxIdle,
+		MaxLifetime:     maxLifetime,
+	}
+	s.huddles[huddleID] = huddle
+	clone := cloneHuddle(huddle)
+	s.mu.Unlock()
+
+	s.emit(HuddleEvent{Type: EventHuddleCreated, HuddleID: huddle.ID, ChannelName: huddle.ChannelName, UserID: createdBy})
+	return clone, nil
+}
+
+func (s *MemoryStore) GetByChannel(ctx context.Context, channelName string) (*Huddle, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, huddle := range s.huddles {
+		if huddle.ChannelName == channelName {
+			return cloneHuddle(huddle), nil
+		}
+	}
+	return nil, fmt.Errorf("huddle not found")
+}
+
+func (s *MemoryStore) Get(ctx context.Context, huddleID string) (*Huddle, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	huddle, exists := s.huddles[huddleID]
+	if !exists {
+		return nil, fmt.Errorf("huddle not found")
+	}
+	return cloneHuddle(huddle), nil
+}
+
+func (s *MemoryStore) List(ctx context.Context) ([]*Huddle, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	huddles := make([]*Huddle, 0, len(s.huddles))
+	for _, huddle := range s.huddles {
+		huddles = append(huddles, cloneHuddle(huddle))
+	}
+	return huddles, nil
+}
+
+func (s *MemoryStore) Join(ctx context.Context, huddleID, userID string) error {
+	s.mu.Lock()
+	huddle, exists := s.huddles[huddleID]
+	if !exists {
+		s.mu.Unlock()
+		return fmt.Errorf("huddle not found")
+	}
+
+	for _, participant := range huddle.Participants {
+		if participant == userID {
+			s.mu.Unlock()
+			return nil // Already in huddle
+		}
+	}
+
+	huddle.Participants = append(huddle.Participants, userID)
+	huddle.LastActivityAt = time.Now().UTC()
+	channelName := huddle.ChannelName
+	s.mu.Unlock()
+
+	s.emit(HuddleEvent{Type: EventParticipantJoined, HuddleID: huddleID, ChannelName: channelName, UserID: userID})
+	return nil
+}
+
+func (s *MemoryStore) Leave(ctx context.Context, huddleID, userID string) error {
+	s.mu.Lock()
+	huddle, exists := s.huddles[huddleID]
+	if !exists {
+		s.mu.Unlock()
+		return fmt.Errorf("huddle not found")
+	}
+
+	removed := false
+	for i, participant := range huddle.Participants {
+		if participant == userID {
+			huddle.Participants = append(huddle.Participants[:i], huddle.Participants[i+1:]...)
+			removed = true
+			break
+		}
+	}
+	channelName := huddle.ChannelName
+	s.mu.Unlock()
+
+	if !removed {
+		return fmt.Errorf("user not in huddle")
+	}
+	s.emit(HuddleEvent{Type: EventParticipantLeft, HuddleID: huddleID, ChannelName: channelName, UserID: userID})
+	return nil
+}
+
+func (s *MemoryStore) End(ctx context.Context, huddleID string) error {
+	s.mu.Lock()
+	huddle, exists := s.huddles[huddleID]
+	if !exists {
+		s.mu.Unlock()
+		return fmt.Errorf("huddle not found")
+	}
+	delete(s.huddles, huddleID)
+	channelName := huddle.ChannelName
+	s.mu.Unlock()
+
+	s.emit(HuddleEvent{Type: EventHuddleEnded, HuddleID: huddleID, ChannelName: channelName})
+	return nil
+}
+
+func (s *MemoryStore) EndByChannel(ctx context.Context, channelName string) error {
+	s.mu.Lock()
+	var huddleID string
+	found := false
+	for id, huddle := range s.huddles {
+		if huddle.ChannelName == channelName {
+			delete(s.huddles, id)
+			huddleID = id
+			found = true
+			break
+		}
+	}
+	s.mu.Unlock()
+
+	if !found {
+		return fmt.Errorf("huddle not found")
+	}
+	s.emit(HuddleEvent{Type: EventHuddleEnded, HuddleID: huddleID, ChannelName: channelName})
+	return nil
+}
+
+func (s *MemoryStore) GetOrCreate(ctx context.Context, channelName, userID string) (*Huddle, error) {
+	s.mu.Lock()
+	for _, huddle := range s.huddles {
+		if huddle.ChannelName == channelName {
+			clone := cloneHuddle(huddle)
+			s.mu.Unlock()
+			return clone, nil
+		}
+	}
+
+	expiresAt, lastActivityAt, maxIdle, maxLifetime := newHuddleLifecycle()
+	huddleID := uuid.New().String()
+	huddle := &Huddle{
+		ID:              huddleID,
+		ChannelName:     channelName,
+		CreatedBy:       userID,
+		CreatedAt:       lastActivityAt,
+		Participants:    []string{},
+		ExpiresAt:       expiresAt,
+		LastActivityAt:  lastActivityAt,
+		MaxIdleDuration: maxIdle,
+		MaxLifetime:     maxLifetime,
+	}
+	s.huddles[huddleID] = huddle
+	clone := cloneHuddle(huddle)
+	s.mu.Unlock()
+
+	s.emit(HuddleEvent{Type: EventHuddleCreated, HuddleID: huddle.ID, ChannelName: huddle.ChannelName, UserID: userID})
+	return clone, nil
+}
+
+func (s *MemoryStore) JoinByChannel(ctx context.Context, channelName, userID string) error {
+	s.mu.Lock()
+	var huddleID string
+	found := false
+	for _, huddle := range s.huddles {
+		if huddle.ChannelName == channelName {
+			found = true
+			huddleID = huddle.ID
+			for _, participant := range huddle.Participants {
+				if participant == userID {
+					s.mu.Unlock()
+					return nil // Already in huddle
+				}
+			}
+			huddle.Participants = append(huddle.Participants, userID)
+			huddle.LastActivityAt = time.Now().UTC()
+			break
+		}
+	}
+	s.mu.Unlock()
+
+	if !found {
+		return fmt.Errorf("huddle not found")
+	}
+	s.emit(HuddleEvent{Type: EventParticipantJoined, HuddleID: huddleID, ChannelName: channelName, UserID: userID})
+	return nil
+}
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisConfig configures the Redis-backed Store.
+type RedisConfig struct {
+	Addr     string
+	Password string
+	DB       int
+}
+
+// RedisConfigFromEnv reads connection settings from REDIS_ADDR,
+// REDIS_PASSWORD and REDIS_DB.
+func RedisConfigFromEnv() RedisConfig {
+	db, _ := parseIntEnv("REDIS_DB", 0)
+	return RedisConfig{
+		Addr:     envOrDefault("REDIS_ADDR", "localhost:6379"),
+		Password: os.Getenv("REDIS_PASSWORD"),
+		DB:       db,
+	}
+}
+
+// RedisStore stores each huddle as a JSON string under huddleKey(id), an
+// index of channel name -> huddle ID so GetByChannel avoids a full scan,
+// and publishes to a pub/sub channel so Watch can fan out events without
+// polling.
+type RedisStore struct {
+	client     *redis.Client
+	channelKey string
+}
+
+const redisEventsChannel = "telex_agora:huddle_events"
+
+// NewRedisStore connects to Redis.
+func NewRedisStore(cfg RedisConfig) (*RedisStore, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     cfg.Addr,
+		Password: cfg.Password,
+		DB:       cfg.DB,
+	})
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		return nil, fmt.Errorf("redis: ping: %w", err)
+	}
+	return &RedisStore{client: client, channelKey: "telex_agora:channel_index"}, nil
+}
+
+func huddleKey(huddleID string) string {
+	return "telex_agora:huddle:" + huddleID
+}
+
+func (s *RedisStore) save(ctx context.Context, huddle *Huddle) error {
+	data, err := json.Marshal(huddle)
+	if err != nil {
+		return fmt.Errorf("redis: marshal huddle: %w", err)
+	}
+	pipe := s.client.TxPipeline()
+	pipe.Set(ctx, huddleKey(huddle.ID), data, 0)
+	pipe.HSet(ctx, s.channelKey, huddle.ChannelName, huddle.ID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("redis: save huddle: %w", err)
+	}
+	return nil
+}
+
+func (s *RedisStore) publish(ctx context.Context, evt HuddleEvent) {
+	evt.Timestamp = time.Now().UTC()
+	data, err := json.Marshal(evt)
+	if err != nil {
+		return
+	}
+	s.client.Publish(ctx, redisEventsChannel, data)
+}
+
+func (s *RedisStore) Create(ctx context.Context, createdBy string) (*Huddle, error) {
+	expiresAt, lastActivityAt, maxIdle, maxLifetime := newHuddleLifecycle()
+	huddle := &Huddle{
+		ID:              newHuddleID(),
+		CreatedBy:       createdBy,
+		CreatedAt:       lastActivityAt,
+		Participants:    []string{},
+		ExpiresAt:       expiresAt,
+		LastActivityAt:  lastActivityAt,
+		MaxIdleDuration: maxIdle,
+		MaxLifetime:     maxLifetime,
+	}
+	huddle.ChannelName = fmt.Sprintf("huddle_%s", huddle.ID[:8])
+	if err := s.save(ctx, huddle); err != nil {
+		return nil, err
+	}
+	s.publish(ctx, HuddleEvent{Type: EventHuddleCreated, HuddleID: huddle.ID, ChannelName: huddle.ChannelName, UserID: createdBy})
+	return huddle, nil
+}
+
+func (s *RedisStore) Get(ctx context.Context, huddleID string) (*Huddle, error) {
+	data, err := s.client.Get(ctx, huddleKey(huddleID)).Bytes()
+	if err == redis.Nil {
+		return nil, fmt.Errorf("huddle not found")
+	} else if err != nil {
+		return nil, fmt.Errorf("redis: get huddle: %w", err)
+	}
+	var huddle Huddle
+	if err := json.Unmarshal(data, &huddle); err != nil {
+		return nil, fmt.Errorf("redis: unmarshal huddle: %w", err)
+	}
+	return &huddle, nil
+}
+
+func (s *RedisStore) GetByChannel(ctx context.Context, channelName string) (*Huddle, error) {
+	huddleID, err := s.client.HGet(ctx, s.channelKey, channelName).Result()
+	if err == redis.Nil {
+		return nil, fmt.Errorf("huddle not found")
+	} else if err != nil {
+		return nil, fmt.Errorf("redis: lookup channel: %w", err)
+	}
+	return s.Get(ctx, huddleID)
+}
+
+func (s *RedisStore) List(ctx context.Context) ([]*Huddle, error) {
+	ids, err := s.client.HVals(ctx, s.channelKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("redis: list huddles: %w", err)
+	}
+	huddles := make([]*Huddle, 0, len(ids))
+	for _, id := range ids {
+		huddle, err := s.Get(ctx, id)
+		if err != nil {
+			continue // removed between HVals and Get
+		}
+		huddles = append(huddles, huddle)
+	}
+	return huddles, nil
+}
+
+func (s *RedisStore) Join(ctx context.Context, huddleID, userID string) error {
+	huddle, err := s.Get(ctx, huddleID)
+	if err != nil {
+		return err
+	}
+	for _, p := range huddle.Participants {
+		if p == userID {
+			return nil
+		}
+	}
+	huddle.Participants = append(huddle.Participants, userID)
+	huddle.LastActivityAt = time.Now().UTC()
+	if err := s.save(ctx, huddle); err != nil {
+		return err
+	}
+	s.publish(ctx, HuddleEvent{Type: EventParticipantJoined, HuddleID: huddleID, ChannelName: huddle.ChannelName, UserID: userID})
+	return nil
+}
+
+func (s *RedisStore) Leave(ctx context.Context, huddleID, userID string) error {
+	huddle, err := s.Get(ctx, huddleID)
+	if err != nil {
+		return err
+	}
+	removed := false
+	for i, p := range huddle.Participants {
+		if p == userID {
+			huddle.Participants = append(huddle.Participants[:i], huddle.Participants[i+1:]...)
+			removed = true
+			break
+		}
+	}
+	if !removed {
+		return fmt.Errorf("user not in huddle")
+	}
+	if err := s.save(ctx, huddle); err != nil {
+		return err
+	}
+	s.publish(ctx, HuddleEvent{Type: EventParticipantLeft, HuddleID: huddleID, ChannelName: huddle.ChannelName, UserID: userID})
+	return nil
+}
+
+func (s *RedisStore) End(ctx context.Context, huddleID string) error {
+	huddle, err := s.Get(ctx, huddleID)
+	if err != nil {
+		return err
+	}
+	pipe := s.client.TxPipeline()
+	pipe.Del(ctx, huddleKey(huddleID))
+	pipe.HDel(ctx, s.channelKey, huddle.ChannelName)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("redis: end huddle: %w", err)
+	}
+	s.publish(ctx, HuddleEvent{Type: EventHuddleEnded, HuddleID: huddleID, ChannelName: huddle.ChannelName})
+	return nil
+}
+
+func (s *RedisStore) EndByChannel(ctx context.Context, channelName string) error {
+	huddle, err := s.GetByChannel(ctx, channelName)
+	if err != nil {
+		return err
+	}
+	return s.End(ctx, huddle.ID)
+}
+
+// GetOrCreate must converge on a single huddle even when two replicas race
+// for the same channel - see the Store interface doc. The plain
+// GetByChannel check below can miss on both replicas, so the create claims
+// the channel with HSetNX first: only the replica that actually sets the
+// index field goes on to write the huddle, and the loser reads back
+// whichever huddle ID won instead of calling save, which would otherwise
+// overwrite the index (HSet keeps only the last writer) and orphan the
+// winner's huddle key.
+func (s *RedisStore) GetOrCreate(ctx context.Context, channelName, userID string) (*Huddle, error) {
+	if huddle, err := s.GetByChannel(ctx, channelName); err == nil {
+		return huddle, nil
+	}
+	expiresAt, lastActivityAt, maxIdle, maxLifetime := newHuddleLifecycle()
+	huddle := &Huddle{
+		ID:              newHuddleID(),
+		ChannelName:     channelName,
+		CreatedBy:       userID,
+		CreatedAt:       lastActivityAt,
+		Participants:    []string{},
+		ExpiresAt:       expiresAt,
+		LastActivityAt:  lastActivityAt,
+		MaxIdleDuration: maxIdle,
+		MaxLifetime:     maxLifetime,
+	}
+	claimed, err := s.client.HSetNX(ctx, s.channelKey, channelName, huddle.ID).Result()
+	if err != nil {
+		return nil, fmt.Errorf("redis: claim channel: %w", err)
+	}
+	if !claimed {
+		huddleID, err := s.client.HGet(ctx, s.channelKey, channelName).Result()
+		if err != nil {
+			return nil, fmt.Errorf("redis: lookup channel: %w", err)
+		}
+		return s.Get(ctx, huddleID)
+	}
+	data, err := json.Marshal(huddle)
+	if err != nil {
+		return nil, fmt.Errorf("redis: marshal huddle: %w", err)
+	}
+	if err := s.client.Set(ctx, huddleKey(huddle.ID), data, 0).Err(); err != nil {
+		return nil, fmt.Errorf("redis: save huddle: %w", err)
+	}
+	s.publish(ctx, HuddleEvent{Type: EventHuddleCreated, HuddleID: huddle.ID, ChannelName: huddle.ChannelName, UserID: userID})
+	return huddle, nil
+}
+
+func (s *RedisStore) JoinByChannel(ctx context.Context, channelName, userID string) error {
+	huddle, err := s.GetByChannel(ctx, channelName)
+	if err != nil {
+		return err
+	}
+	return s.Join(ctx, huddle.ID, userID)
+}
+
+func (s *RedisStore) SetRecording(ctx context.Context, huddleID string, recording *RecordingState) (*Huddle, error) {
+	huddle, err := s.Get(ctx, huddleID)
+	if err != nil {
+		return nil, err
+	}
+	huddle.Recording = recording
+	if err := s.save(ctx, huddle); err != nil {
+		return nil, err
+	}
+	s.publish(ctx, recordingEvent(huddleID, huddle.ChannelName, recording))
+	return huddle, nil
+}
+
+func (s *RedisStore) SetRole(ctx context.Context, huddleID, userID string, role Role) (*Huddle, error) {
+	huddle, err := s.Get(ctx, huddleID)
+	if err != nil {
+		return nil, err
+	}
+	if huddle.Roles == nil {
+		huddle.Roles = make(map[string]Role)
+	}
+	huddle.Roles[userID] = role
+	if err := s.save(ctx, huddle); err != nil {
+		return nil, err
+	}
+	return huddle, nil
+}
+
+func (s *RedisStore) Extend(ctx context.Context, huddleID string, by time.Duration) (*Huddle, error) {
+	huddle, err := s.Get(ctx, huddleID)
+	if err != nil {
+		return nil, err
+	}
+	huddle.ExpiresAt = huddle.ExpiresAt.Add(by)
+	if err := s.save(ctx, huddle); err != nil {
+		return nil, err
+	}
+	return huddle, nil
+}
+
+func (s *RedisStore) Watch(ctx context.Context) (<-chan HuddleEvent, error) {
+	pubsub := s.client.Subscribe(ctx, redisEventsChannel)
+	out := make(chan HuddleEvent, 32)
+
+	go func() {
+		defer close(out)
+		defer pubsub.Close()
+		ch := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-ch:
+				if !ok {
+					return
+				}
+				var evt HuddleEvent
+				if json.Unmarshal([]byte(msg.Payload), &evt) == nil {
+					out <- evt
+				}
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
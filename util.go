@@ -0,0 +1,43 @@
+package main
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+// envOrDefault returns the environment variable's value, or def if unset.
+func envOrDefault(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// parseIntEnv parses the environment variable as an int, returning def if
+// unset or invalid.
+func parseIntEnv(key string, def int) (int, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return def, err
+	}
+	return n, nil
+}
+
+// envDuration parses the environment variable with time.ParseDuration,
+// returning def if unset or invalid.
+func envDuration(key string, def time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return def
+	}
+	return d
+}
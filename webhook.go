@@ -0,0 +1,108 @@
+package main
+
+import (
+	"crypto/subtle"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// WebhookSubscription is one operator-registered URL that receives signed
+// huddle lifecycle event deliveries.
+type WebhookSubscription struct {
+	ID  string `json:"id"`
+	URL string `json:"url"`
+}
+
+// webhookRegistry tracks registered subscriptions, seeded from
+// HUDDLE_WEBHOOK_URLS and extendable at runtime via POST /webhooks.
+type webhookRegistry struct {
+	mu   sync.RWMutex
+	subs map[string]WebhookSubscription
+}
+
+func newWebhookRegistry() *webhookRegistry {
+	return &webhookRegistry{subs: make(map[string]WebhookSubscription)}
+}
+
+// webhookRegistryFromEnv seeds a registry from HUDDLE_WEBHOOK_URLS, a
+// comma-separated list of URLs.
+func webhookRegistryFromEnv() *webhookRegistry {
+	r := newWebhookRegistry()
+	for _, url := range strings.Split(os.Getenv("HUDDLE_WEBHOOK_URLS"), ",") {
+		url = strings.TrimSpace(url)
+		if url == "" {
+			continue
+		}
+		r.add(url)
+	}
+	return r
+}
+
+func (r *webhookRegistry) add(url string) WebhookSubscription {
+	sub := WebhookSubscription{ID: uuid.New().String(), URL: url}
+	r.mu.Lock()
+	r.subs[sub.ID] = sub
+	r.mu.Unlock()
+	return sub
+}
+
+func (r *webhookRegistry) list() []WebhookSubscription {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	subs := make([]WebhookSubscription, 0, len(r.subs))
+	for _, sub := range r.subs {
+		subs = append(subs, sub)
+	}
+	return subs
+}
+
+// RequireOperator gates /webhooks behind a shared operator token rather
+// than an ordinary user's auth token: registered URLs receive every
+// huddle's lifecycle events, including participant identities, so any
+// authenticated Telex user being able to register or list them would be a
+// cross-tenant data leak. HUDDLE_ADMIN_TOKEN is provisioned to operators
+// out of band, the same way POSTGRES_DSN/ETCD_ENDPOINTS configure other
+// operator-only concerns. If it's unset, every request is rejected rather
+// than left open.
+func RequireOperator() gin.HandlerFunc {
+	token := os.Getenv("HUDDLE_ADMIN_TOKEN")
+	return func(c *gin.Context) {
+		if token == "" {
+			c.AbortWithStatusJSON(500, gin.H{"error": "HUDDLE_ADMIN_TOKEN is not configured"})
+			return
+		}
+		supplied := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(supplied), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(403, gin.H{"error": "operator token required"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// registerWebhook lets an operator subscribe a URL to huddle lifecycle
+// events at runtime, in addition to whatever HUDDLE_WEBHOOK_URLS seeded.
+// Guarded by RequireOperator, not RequireAuth.
+func registerWebhook(c *gin.Context) {
+	var req struct {
+		URL string `json:"url" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "url is required"})
+		return
+	}
+
+	sub := webhookDispatcher.registry.add(req.URL)
+	c.JSON(201, sub)
+}
+
+// listWebhooks returns every registered subscription. Guarded by
+// RequireOperator, not RequireAuth, for the same reason as registerWebhook.
+func listWebhooks(c *gin.Context) {
+	c.JSON(200, gin.H{"webhooks": webhookDispatcher.registry.list()})
+}
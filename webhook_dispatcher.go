@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+)
+
+const (
+	webhookWorkerCount = 4
+	webhookQueueSize   = 256
+	webhookMaxAttempts = 5
+)
+
+// WebhookPayload is the JSON body POSTed to every subscribed URL.
+type WebhookPayload struct {
+	Type        HuddleEventType `json:"type"`
+	HuddleID    string          `json:"huddle_id"`
+	ChannelName string          `json:"channel_name"`
+	UserID      string          `json:"user_id,omitempty"`
+	Timestamp   time.Time       `json:"timestamp"`
+}
+
+type webhookDelivery struct {
+	url     string
+	payload WebhookPayload
+}
+
+// WebhookDispatcher fans HuddleEvents out to every registered webhook URL
+// through a bounded worker pool, retrying failed deliveries with
+// exponential backoff and logging ones that exhaust their retries instead
+// of dropping them silently.
+type WebhookDispatcher struct {
+	registry *webhookRegistry
+	secret   string
+	client   *http.Client
+	queue    chan webhookDelivery
+}
+
+// NewWebhookDispatcher builds a dispatcher backed by registry and starts
+// its worker pool. Deliveries are signed with HUDDLE_WEBHOOK_SECRET,
+// falling back to APP_CERTIFICATE so a fresh deployment has a usable
+// secret without extra configuration.
+func NewWebhookDispatcher(registry *webhookRegistry) *WebhookDispatcher {
+	secret := os.Getenv("HUDDLE_WEBHOOK_SECRET")
+	if secret == "" {
+		secret = appCertificate
+	}
+
+	d := &WebhookDispatcher{
+		registry: registry,
+		secret:   secret,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		queue:    make(chan webhookDelivery, webhookQueueSize),
+	}
+	for i := 0; i < webhookWorkerCount; i++ {
+		go d.worker()
+	}
+	return d
+}
+
+// WatchStore subscribes to the huddle store's event stream and enqueues a
+// delivery to every registered URL for each event, the same way chatHub
+// and the metrics watcher consume the same stream for their own purposes.
+func (d *WebhookDispatcher) WatchStore(ctx context.Context, store Store) {
+	events, err := store.Watch(ctx)
+	if err != nil {
+		log.Printf("webhook: watch store: %v", err)
+		return
+	}
+	for evt := range events {
+		payload := WebhookPayload{
+			Type:        evt.Type,
+			HuddleID:    evt.HuddleID,
+			ChannelName: evt.ChannelName,
+			UserID:      evt.UserID,
+			Timestamp:   evt.Timestamp,
+		}
+		for _, sub := range d.registry.list() {
+			select {
+			case d.queue <- webhookDelivery{url: sub.URL, payload: payload}:
+			default:
+				log.Printf("webhook: queue full, dropping delivery to %s for %s", sub.URL, evt.Type)
+			}
+		}
+	}
+}
+
+func (d *WebhookDispatcher) worker() {
+	for delivery := range d.queue {
+		d.deliver(delivery)
+	}
+}
+
+// deliver POSTs the signed payload, retrying on failure with exponential
+// backoff (1s, 2s, 4s, 8s) before giving up and dead-lettering it.
+func (d *WebhookDispatcher) deliver(delivery webhookDelivery) {
+	body, err := json.Marshal(delivery.payload)
+	if err != nil {
+		log.Printf("webhook: marshal payload: %v", err)
+		return
+	}
+	signature := d.sign(body)
+
+	var lastErr error
+	for attempt := 0; attempt < webhookMaxAttempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(time.Duration(1<<uint(attempt-1)) * time.Second)
+		}
+
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, delivery.url, bytes.NewReader(body))
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Huddle-Signature", signature)
+
+		resp, err := d.client.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode < 300 {
+			return
+		}
+		lastErr = fmt.Errorf("webhook: %s returned %d", delivery.url, resp.StatusCode)
+	}
+
+	d.deadLetter(delivery, lastErr)
+}
+
+func (d *WebhookDispatcher) sign(body []byte) string {
+	mac := hmac.New(sha256.New, []byte(d.secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// deadLetter records a delivery that exhausted every retry. This is a
+// structured log line rather than a separate store - operators are
+// expected to alert on this pattern and re-register/backfill out of band.
+func (d *WebhookDispatcher) deadLetter(delivery webhookDelivery, err error) {
+	log.Printf("webhook dead-letter: url=%s type=%s huddle=%s err=%v",
+		delivery.url, delivery.payload.Type, delivery.payload.HuddleID, err)
+}